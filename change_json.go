@@ -0,0 +1,162 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+// registry of concrete types that are preserved when serializing a changelog to json
+var types = struct {
+	sync.RWMutex
+	names map[reflect.Type]string
+	types map[string]reflect.Type
+}{
+	names: make(map[reflect.Type]string),
+	types: make(map[string]reflect.Type),
+}
+
+// RegisterType registers the concrete type of sample under name. When TypedValues is enabled and a
+// change's From or To value is of a registered type, its name is recorded alongside the value when
+// marshaling to json, so that the concrete type can be rebuilt when unmarshaling. This allows values
+// held in interface fields to be patched from a deserialized changelog
+func RegisterType(name string, sample interface{}) {
+	t := reflect.TypeOf(sample)
+
+	types.Lock()
+	defer types.Unlock()
+
+	types.names[t] = name
+	types.types[name] = t
+}
+
+type jsonChange struct {
+	Type     string      `json:"type"`
+	Path     []string    `json:"path"`
+	From     interface{} `json:"from"`
+	To       interface{} `json:"to"`
+	FromType string      `json:"fromType,omitempty"`
+	ToType   string      `json:"toType,omitempty"`
+
+	FromIndex *int `json:"fromIndex,omitempty"`
+	ToIndex   *int `json:"toIndex,omitempty"`
+
+	FromFormatted string `json:"fromFormatted,omitempty"`
+	ToFormatted   string `json:"toFormatted,omitempty"`
+
+	FromKind string `json:"fromKind,omitempty"`
+	ToKind   string `json:"toKind,omitempty"`
+
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+type jsonRawChange struct {
+	Type     string          `json:"type"`
+	Path     []string        `json:"path"`
+	From     json.RawMessage `json:"from"`
+	To       json.RawMessage `json:"to"`
+	FromType string          `json:"fromType"`
+	ToType   string          `json:"toType"`
+
+	FromIndex *int `json:"fromIndex"`
+	ToIndex   *int `json:"toIndex"`
+
+	FromFormatted string `json:"fromFormatted"`
+	ToFormatted   string `json:"toFormatted"`
+
+	FromKind string `json:"fromKind"`
+	ToKind   string `json:"toKind"`
+
+	Redacted bool `json:"redacted"`
+}
+
+// MarshalJSON implements json.Marshaler, recording the type names attached by TypedValues
+func (c Change) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonChange{
+		Type:     c.Type,
+		Path:     c.Path,
+		From:     c.From,
+		To:       c.To,
+		FromType: c.FromType,
+		ToType:   c.ToType,
+
+		FromIndex: c.FromIndex,
+		ToIndex:   c.ToIndex,
+
+		FromFormatted: c.FromFormatted,
+		ToFormatted:   c.ToFormatted,
+
+		FromKind: c.FromKind,
+		ToKind:   c.ToKind,
+
+		Redacted: c.Redacted,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, rebuilding values of registered types
+func (c *Change) UnmarshalJSON(data []byte) error {
+	var jc jsonRawChange
+
+	err := json.Unmarshal(data, &jc)
+	if err != nil {
+		return err
+	}
+
+	c.Type = jc.Type
+	c.Path = jc.Path
+	c.FromIndex = jc.FromIndex
+	c.ToIndex = jc.ToIndex
+	c.FromFormatted = jc.FromFormatted
+	c.ToFormatted = jc.ToFormatted
+	c.FromKind = jc.FromKind
+	c.ToKind = jc.ToKind
+	c.Redacted = jc.Redacted
+	c.FromType = jc.FromType
+	c.ToType = jc.ToType
+
+	c.From, err = typedValue(jc.FromType, jc.From)
+	if err != nil {
+		return err
+	}
+
+	c.To, err = typedValue(jc.ToType, jc.To)
+
+	return err
+}
+
+func typeName(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+
+	types.RLock()
+	defer types.RUnlock()
+
+	return types.names[reflect.TypeOf(v)]
+}
+
+func typedValue(name string, data json.RawMessage) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	types.RLock()
+	t, ok := types.types[name]
+	types.RUnlock()
+
+	if !ok {
+		var v interface{}
+		return v, json.Unmarshal(data, &v)
+	}
+
+	v := reflect.New(t)
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return nil, NewError("Unable to unmarshal value to registered type "+name, err)
+	}
+
+	return v.Elem().Interface(), nil
+}
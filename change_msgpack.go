@@ -0,0 +1,197 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"reflect"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// basic types are rebuilt exactly, as msgpack would otherwise decode an int to the smallest
+// type that holds its value
+var basicTypes = map[string]reflect.Type{}
+
+func init() {
+	for _, v := range []interface{}{
+		false, "",
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0),
+	} {
+		t := reflect.TypeOf(v)
+		basicTypes[t.Name()] = t
+	}
+}
+
+type msgpackChange struct {
+	Type       string             `msgpack:"type"`
+	Path       []string           `msgpack:"path"`
+	From       msgpack.RawMessage `msgpack:"from"`
+	To         msgpack.RawMessage `msgpack:"to"`
+	Parent     msgpack.RawMessage `msgpack:"parent,omitempty"`
+	FromType   string             `msgpack:"fromType,omitempty"`
+	ToType     string             `msgpack:"toType,omitempty"`
+	ParentType string             `msgpack:"parentType,omitempty"`
+
+	FromIndex *int `msgpack:"fromIndex,omitempty"`
+	ToIndex   *int `msgpack:"toIndex,omitempty"`
+
+	FromFormatted string `msgpack:"fromFormatted,omitempty"`
+	ToFormatted   string `msgpack:"toFormatted,omitempty"`
+
+	FromKind string `msgpack:"fromKind,omitempty"`
+	ToKind   string `msgpack:"toKind,omitempty"`
+
+	Redacted bool `msgpack:"redacted,omitempty"`
+}
+
+// MarshalMsgpack implements msgpack.Marshaler, encoding the changelog along with the type names of
+// its values. Values of registered and basic types are rebuilt with their concrete type by
+// UnmarshalChangelog, though interface fields within them are decoded loosely. The parent of each
+// change is kept where it can be encoded
+func (cl Changelog) MarshalMsgpack() ([]byte, error) {
+	mcl := make([]msgpackChange, len(cl))
+
+	for i, c := range cl {
+		from, err := msgpack.Marshal(c.From)
+		if err != nil {
+			return nil, err
+		}
+
+		to, err := msgpack.Marshal(c.To)
+		if err != nil {
+			return nil, err
+		}
+
+		mcl[i] = msgpackChange{
+			Type:     c.Type,
+			Path:     c.Path,
+			From:     from,
+			To:       to,
+			FromType: msgpackTypeName(c.From),
+			ToType:   msgpackTypeName(c.To),
+
+			FromIndex: c.FromIndex,
+			ToIndex:   c.ToIndex,
+
+			FromFormatted: c.FromFormatted,
+			ToFormatted:   c.ToFormatted,
+
+			FromKind: c.FromKind,
+			ToKind:   c.ToKind,
+
+			Redacted: c.Redacted,
+		}
+
+		// the parent is only informative, so it is left out when it holds values that can't be encoded
+		if c.parent != nil {
+			if parent, err := msgpack.Marshal(c.parent); err == nil {
+				mcl[i].Parent = parent
+				mcl[i].ParentType = msgpackTypeName(c.parent)
+			}
+		}
+	}
+
+	return msgpack.Marshal(mcl)
+}
+
+// UnmarshalMsgpack implements msgpack.Unmarshaler, rebuilding values of registered and basic types
+func (cl *Changelog) UnmarshalMsgpack(data []byte) error {
+	var mcl []msgpackChange
+
+	err := msgpack.Unmarshal(data, &mcl)
+	if err != nil {
+		return err
+	}
+
+	ncl := make(Changelog, len(mcl))
+
+	for i, mc := range mcl {
+		c := Change{
+			Type:      mc.Type,
+			Path:      mc.Path,
+			FromIndex: mc.FromIndex,
+			ToIndex:   mc.ToIndex,
+
+			FromFormatted: mc.FromFormatted,
+			ToFormatted:   mc.ToFormatted,
+
+			FromKind: mc.FromKind,
+			ToKind:   mc.ToKind,
+
+			Redacted: mc.Redacted,
+		}
+
+		if c.From, err = msgpackValue(mc.FromType, mc.From); err != nil {
+			return err
+		}
+
+		if c.To, err = msgpackValue(mc.ToType, mc.To); err != nil {
+			return err
+		}
+
+		if c.parent, err = msgpackValue(mc.ParentType, mc.Parent); err != nil {
+			return err
+		}
+
+		ncl[i] = c
+	}
+
+	*cl = ncl
+
+	return nil
+}
+
+// UnmarshalChangelog decodes a changelog encoded with MarshalMsgpack
+func UnmarshalChangelog(data []byte) (Changelog, error) {
+	var cl Changelog
+	err := cl.UnmarshalMsgpack(data)
+	return cl, err
+}
+
+// msgpackTypeName returns the name of a registered type, or of a basic type
+func msgpackTypeName(v interface{}) string {
+	if name := typeName(v); name != "" {
+		return name
+	}
+
+	if v == nil {
+		return ""
+	}
+
+	t := reflect.TypeOf(v)
+	if basicTypes[t.Name()] == t {
+		return t.Name()
+	}
+
+	return ""
+}
+
+func msgpackValue(name string, data msgpack.RawMessage) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	types.RLock()
+	t, ok := types.types[name]
+	types.RUnlock()
+
+	if !ok {
+		t, ok = basicTypes[name]
+	}
+
+	if !ok {
+		var v interface{}
+		return v, msgpack.Unmarshal(data, &v)
+	}
+
+	v := reflect.New(t)
+	if err := msgpack.Unmarshal(data, v.Interface()); err != nil {
+		return nil, NewError("Unable to unmarshal value to type "+name, err)
+	}
+
+	return v.Elem().Interface(), nil
+}
@@ -131,14 +131,14 @@ func (c *ChangeValue) Set(value reflect.Value, convertCompatibleTypes bool) {
 				c.target.Set(reflect.Zero(c.target.Type()))
 				c.SetFlag(FlagApplied)
 				return
-			} else if !value.Type().ConvertibleTo(c.target.Elem().Type()) {
+			} else if !value.Type().ConvertibleTo(c.target.Type().Elem()) {
 				c.AddError(fmt.Errorf("Value of type %s is not convertible to %s", value.Type().String(), c.target.Type().String()))
 				c.SetFlag(FlagFailed)
 				return
 			}
 
-			tv := reflect.New(c.target.Elem().Type())
-			tv.Elem().Set(value.Convert(c.target.Elem().Type()))
+			tv := reflect.New(c.target.Type().Elem())
+			tv.Elem().Set(value.Convert(c.target.Type().Elem()))
 			c.target.Set(tv)
 		} else {
 			if !value.Type().ConvertibleTo(c.target.Type()) {
@@ -151,14 +151,21 @@ func (c *ChangeValue) Set(value reflect.Value, convertCompatibleTypes bool) {
 		}
 	} else {
 		if value.IsValid() {
-			if c.target.Kind() == reflect.Ptr && value.Kind() != reflect.Ptr {
-				tv := reflect.New(value.Type())
-				tv.Elem().Set(value)
-				c.target.Set(tv)
+			if c.target.Kind() == reflect.Ptr && !value.Type().AssignableTo(c.target.Type()) {
+				c.target.Set(pointerTo(value, c.target.Type()))
 			} else {
 				c.target.Set(value)
 			}
 		} else if c.target.Kind() == reflect.Ptr {
+			//for nested pointers, the type of the original value tells
+			//us which level of the pointer was set to nil
+			if from := reflect.ValueOf(c.change.From); from.IsValid() &&
+				from.Type() != c.target.Type() && from.Type().AssignableTo(c.target.Type().Elem()) {
+				c.target.Set(reflect.New(c.target.Type().Elem()))
+			} else {
+				c.target.Set(reflect.Zero(c.target.Type()))
+			}
+		} else if c.target.Kind() == reflect.Interface {
 			c.target.Set(reflect.Zero(c.target.Type()))
 		} else if !c.target.IsZero() {
 			t := c.target.Elem()
@@ -168,6 +175,22 @@ func (c *ChangeValue) Set(value reflect.Value, convertCompatibleTypes bool) {
 	c.SetFlag(FlagApplied)
 }
 
+//pointerTo wraps value in as many pointers as needed to match t, so
+//values can be set on nested pointers such as **int
+func pointerTo(value reflect.Value, t reflect.Type) reflect.Value {
+	if value.Type().AssignableTo(t) || t.Kind() != reflect.Ptr {
+		return value
+	}
+
+	tv := reflect.New(t.Elem())
+	if !value.Type().AssignableTo(t.Elem()) && t.Elem().Kind() != reflect.Ptr {
+		// keep the previous behaviour of allocating by the value's own type
+		tv = reflect.New(value.Type())
+	}
+	tv.Elem().Set(pointerTo(value, tv.Type().Elem()))
+	return tv
+}
+
 //Index echo for index
 func (c ChangeValue) Index(i int) reflect.Value {
 	return c.target.Index(i)
@@ -200,6 +223,26 @@ func (c ChangeValue) NewArrayElement() reflect.Value {
 	return c.Index(c.Len() - 1)
 }
 
+//GrowArrayElement grows the slice with zero values up to index i, and
+//places a new element at i
+func (c *ChangeValue) GrowArrayElement(i int) reflect.Value {
+	gap := reflect.MakeSlice(c.target.Type(), i-c.Len(), i-c.Len())
+	s := reflect.AppendSlice(*c.target, gap)
+	c.target.Set(reflect.Append(s, c.NewElement()))
+	c.SetFlag(FlagCreated)
+	return c.Index(i)
+}
+
+//InsertArrayElement inserts a new element at index i, shifting the following elements
+func (c ChangeValue) InsertArrayElement(i int) reflect.Value {
+	s := reflect.Append(*c.target, c.NewElement())
+	reflect.Copy(s.Slice(i+1, s.Len()), s.Slice(i, s.Len()-1))
+	s.Index(i).Set(c.NewElement())
+	c.target.Set(s)
+	c.SetFlag(FlagCreated)
+	return c.Index(i)
+}
+
 //AddError appends errors to this change value
 func (c *ChangeValue) AddError(err error) *ChangeValue {
 	if c != nil {
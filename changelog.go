@@ -0,0 +1,395 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Compact merges runs of adjacent CREATE or DELETE changes on consecutive indexes of the same
+// slice into a single change. The merged change is located at the first index of the run and
+// carries a sub-slice of all values in the run. Compacted changelogs are intended for reporting,
+// they cannot be applied with Patch
+func (cl Changelog) Compact() Changelog {
+	var ncl Changelog
+
+	for i := 0; i < len(cl); {
+		j := i + 1
+		for j < len(cl) && adjacent(cl[j-1], cl[j]) {
+			j++
+		}
+
+		if j-i == 1 {
+			ncl = append(ncl, cl[i])
+			i = j
+			continue
+		}
+
+		c := Change{
+			Type: cl[i].Type,
+			Path: cl[i].Path,
+		}
+
+		values := make([]interface{}, 0, j-i)
+		for _, rc := range cl[i:j] {
+			if rc.Type == CREATE {
+				values = append(values, rc.To)
+			} else {
+				values = append(values, rc.From)
+			}
+		}
+
+		if c.Type == CREATE {
+			c.To = subSlice(values)
+		} else {
+			c.From = subSlice(values)
+		}
+
+		ncl = append(ncl, c)
+		i = j
+	}
+
+	return ncl
+}
+
+// adjacent returns true if b follows a on the next index of the same slice
+func adjacent(a, b Change) bool {
+	if a.Type != b.Type || (a.Type != CREATE && a.Type != DELETE) {
+		return false
+	}
+
+	if len(a.Path) == 0 || len(a.Path) != len(b.Path) {
+		return false
+	}
+
+	last := len(a.Path) - 1
+	if !Path(a.Path[:last]).Equal(b.Path[:last]) {
+		return false
+	}
+
+	ai, err := strconv.Atoi(a.Path[last])
+	if err != nil {
+		return false
+	}
+
+	bi, err := strconv.Atoi(b.Path[last])
+	if err != nil {
+		return false
+	}
+
+	return bi == ai+1
+}
+
+// subSlice builds a slice from values, using their concrete type if they all share the same one
+func subSlice(values []interface{}) interface{} {
+	t := reflect.TypeOf(values[0])
+	for _, v := range values {
+		if reflect.TypeOf(v) != t {
+			return values
+		}
+	}
+
+	if t == nil {
+		return values
+	}
+
+	s := reflect.MakeSlice(reflect.SliceOf(t), len(values), len(values))
+	for i, v := range values {
+		s.Index(i).Set(reflect.ValueOf(v))
+	}
+
+	return s.Interface()
+}
+
+// Squash collapses all changes that share an identical path into a single net change, going
+// from the first change's original value to the last change's resulting value. Changes with
+// no net effect, such as a CREATE followed by a DELETE, or updates that restore the original
+// value, are dropped. Distinct paths are kept in order of their first occurrence
+func (cl Changelog) Squash() Changelog {
+	var order []string
+	groups := make(map[string][]Change)
+
+	for _, c := range cl {
+		k := strings.Join(c.Path, "\x00")
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], c)
+	}
+
+	var ncl Changelog
+	for _, k := range order {
+		g := groups[k]
+		if len(g) == 1 {
+			ncl = append(ncl, g[0])
+			continue
+		}
+
+		if c, ok := squash(g[0], g[len(g)-1]); ok {
+			ncl = append(ncl, c)
+		}
+	}
+
+	return ncl
+}
+
+// Clean removes changes that have no effect. Updates to an equal value, unchanged values, changes
+// with an empty path and no difference between their values, and repeats of the previous change
+// to the same path are all dropped
+func (cl Changelog) Clean() Changelog {
+	var ncl Changelog
+	last := make(map[string]Change)
+
+	for _, c := range cl {
+		noop := reflect.DeepEqual(c.From, c.To)
+		if noop && (c.Type == UPDATE || c.Type == EQUAL || len(c.Path) == 0) {
+			continue
+		}
+
+		k := strings.Join(c.Path, "\x00")
+		if p, ok := last[k]; ok && p.Type == c.Type && reflect.DeepEqual(p.From, c.From) && reflect.DeepEqual(p.To, c.To) {
+			continue
+		}
+		last[k] = c
+
+		ncl = append(ncl, c)
+	}
+
+	return ncl
+}
+
+// squash returns the net change between first and last, if there is one
+func squash(first, last Change) (Change, bool) {
+	existed := first.Type != CREATE
+	exists := last.Type != DELETE
+
+	c := Change{Path: first.Path, parent: first.parent, Redacted: first.Redacted || last.Redacted}
+
+	switch {
+	case existed && exists:
+		if reflect.DeepEqual(first.From, last.To) {
+			return c, false
+		}
+		c.Type, c.From, c.To = UPDATE, first.From, last.To
+		c.FromFormatted, c.ToFormatted = first.FromFormatted, last.ToFormatted
+		c.FromKind, c.ToKind = first.FromKind, last.ToKind
+		c.FromType, c.ToType = first.FromType, last.ToType
+	case existed:
+		c.Type, c.From, c.FromFormatted, c.FromKind = DELETE, first.From, first.FromFormatted, first.FromKind
+		c.FromType = first.FromType
+	case exists:
+		c.Type, c.To, c.ToFormatted, c.ToKind = CREATE, last.To, last.ToFormatted, last.ToKind
+		c.ToType = last.ToType
+	default:
+		return c, false
+	}
+
+	return c, true
+}
+
+// format renders the values of each change with f
+func (cl Changelog) format(f func(v interface{}) string) {
+	for i := range cl {
+		if cl[i].From != nil {
+			cl[i].FromFormatted = f(cl[i].From)
+		}
+		if cl[i].To != nil {
+			cl[i].ToFormatted = f(cl[i].To)
+		}
+	}
+}
+
+// attachKinds records the kinds of the values of each change
+func (cl Changelog) attachKinds() {
+	for i := range cl {
+		if cl[i].From != nil {
+			cl[i].FromKind = reflect.ValueOf(cl[i].From).Kind().String()
+		}
+		if cl[i].To != nil {
+			cl[i].ToKind = reflect.ValueOf(cl[i].To).Kind().String()
+		}
+	}
+}
+
+// attachTypes records the registered type names of the values of each change
+func (cl Changelog) attachTypes() {
+	for i := range cl {
+		cl[i].FromType = typeName(cl[i].From)
+		cl[i].ToType = typeName(cl[i].To)
+	}
+}
+
+// Reverse returns a changelog that undoes the changes of cl. Changes are inverted and their order is
+// reversed, so that applying the result with Patch restores the original value. Map changes carry the
+// key in their path and the value in From or To, so they can always be reversed
+func (cl Changelog) Reverse() Changelog {
+	rcl := make(Changelog, 0, len(cl))
+
+	for i := len(cl) - 1; i >= 0; i-- {
+		c := cl[i]
+
+		switch c.Type {
+		case CREATE:
+			c.Type = DELETE
+		case DELETE:
+			c.Type = CREATE
+		}
+
+		c.From, c.To = c.To, c.From
+		c.FromFormatted, c.ToFormatted = c.ToFormatted, c.FromFormatted
+		c.FromKind, c.ToKind = c.ToKind, c.FromKind
+		c.FromType, c.ToType = c.ToType, c.FromType
+		c.FromIndex, c.ToIndex = c.ToIndex, c.FromIndex
+		c.parent = nil
+
+		rcl = append(rcl, c)
+	}
+
+	return rcl
+}
+
+// GroupByPathPrefix buckets changes by their first depth path segments, joined with a dot.
+// Changes with shorter paths are placed in a bucket keyed by their full path
+func (cl Changelog) GroupByPathPrefix(depth int) map[string]Changelog {
+	groups := make(map[string]Changelog)
+
+	for _, c := range cl {
+		p := Path(c.Path)
+		if len(p) > depth {
+			p = p[:depth]
+		}
+
+		k := p.String()
+		groups[k] = append(groups[k], c)
+	}
+
+	return groups
+}
+
+// NestByComparative groups the changes that belong to identified slice elements by element. Groups are
+// keyed by the path up to and including the identifier of the innermost element a change belongs to, and
+// hold the changes with their path relative to that element. Elements are only recorded on changelogs
+// produced with TrackComparativeIndices, and all other changes are left out
+func (cl Changelog) NestByComparative() map[string]Changelog {
+	groups := make(map[string]Changelog)
+
+	for _, c := range cl {
+		if c.element == 0 || c.element > len(c.Path) {
+			continue
+		}
+
+		k := Path(c.Path[:c.element]).String()
+
+		nc := c
+		nc.Path = c.Path[c.element:]
+
+		groups[k] = append(groups[k], nc)
+	}
+
+	return groups
+}
+
+// Paths returns the paths of all changes, without duplicates, in order of their first occurrence
+func (cl Changelog) Paths() [][]string {
+	var paths [][]string
+	seen := make(map[string]bool)
+
+	for _, c := range cl {
+		k := strings.Join(c.Path, "\x00")
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		paths = append(paths, c.Path)
+	}
+
+	return paths
+}
+
+// TopLevelFields returns the first segment of the paths of all changes, without duplicates, in
+// order of their first occurrence. Map keys encoded with StructMapKeySupport are decoded
+func (cl Changelog) TopLevelFields() []string {
+	var fields []string
+	seen := make(map[string]bool)
+
+	for _, c := range cl {
+		if len(c.Path) == 0 {
+			continue
+		}
+
+		f := decodeSegment(c.Path[0])
+		if seen[f] {
+			continue
+		}
+		seen[f] = true
+		fields = append(fields, f)
+	}
+
+	return fields
+}
+
+// Sort sorts the changelog in place by path, comparing the paths segment by segment, then by
+// type. Changes that share both are ordered by their values, so equal changelogs always sort to
+// the same order
+func (cl Changelog) Sort() {
+	sort.SliceStable(cl, func(i, j int) bool {
+		if n := comparePaths(cl[i].Path, cl[j].Path); n != 0 {
+			return n < 0
+		}
+		if cl[i].Type != cl[j].Type {
+			return cl[i].Type < cl[j].Type
+		}
+		if f, g := formatValue(cl[i].From), formatValue(cl[j].From); f != g {
+			return f < g
+		}
+		return formatValue(cl[i].To) < formatValue(cl[j].To)
+	})
+}
+
+// comparePaths compares paths segment by segment. A path sorts before any longer path it is a
+// prefix of
+func comparePaths(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if n := strings.Compare(a[i], b[i]); n != 0 {
+			return n
+		}
+	}
+	return len(a) - len(b)
+}
+
+// String returns a human readable representation of the change, such as
+// `UPDATE name: "one" -> "two"`
+func (c Change) String() string {
+	s := fmt.Sprintf("%s %s:", strings.ToUpper(c.Type), Path(c.Path))
+
+	switch c.Type {
+	case CREATE:
+		return s + " " + formatValue(c.To)
+	case DELETE:
+		return s + " " + formatValue(c.From)
+	}
+
+	return s + " " + formatValue(c.From) + " -> " + formatValue(c.To)
+}
+
+// String returns each change of the changelog on its own line
+func (cl Changelog) String() string {
+	lines := make([]string, len(cl))
+	for i, c := range cl {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(v)
+}
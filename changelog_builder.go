@@ -0,0 +1,67 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+// ChangelogBuilder constructs changelogs by hand, such as from external events, that can be
+// applied with Patch in the same way as a changelog produced by Diff
+type ChangelogBuilder struct {
+	d  *Differ
+	cl Changelog
+}
+
+// NewChangelogBuilder creates a builder configured with the same options as a Differ. The
+// StructMapKeySupport option determines how map keys are encoded by Key
+func NewChangelogBuilder(opts ...func(d *Differ) error) (*ChangelogBuilder, error) {
+	d, err := NewDiffer(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChangelogBuilder{d: d}, nil
+}
+
+// Key encodes a map key as a path segment, as Diff would. Keys that aren't strings or ints are
+// msgpack encoded when StructMapKeySupport is enabled, so they can be decoded again by Patch
+func (b *ChangelogBuilder) Key(k interface{}) string {
+	if b.d.StructMapKeys {
+		return idComplex(k)
+	}
+	return idstring(k)
+}
+
+// Update adds the update of the value at path
+func (b *ChangelogBuilder) Update(path []string, from, to interface{}) *ChangelogBuilder {
+	b.cl.Add(UPDATE, copyAppend(path), from, to)
+	return b
+}
+
+// Create adds the creation of the value at path
+func (b *ChangelogBuilder) Create(path []string, to interface{}) *ChangelogBuilder {
+	b.cl.Add(CREATE, copyAppend(path), nil, to)
+	return b
+}
+
+// Delete adds the deletion of the value at path
+func (b *ChangelogBuilder) Delete(path []string, from interface{}) *ChangelogBuilder {
+	b.cl.Add(DELETE, copyAppend(path), from, nil)
+	return b
+}
+
+// Build returns the changes added so far, formatting their values if a ValueFormatter is set
+// and recording their kinds if AttachKinds is enabled
+func (b *ChangelogBuilder) Build() Changelog {
+	cl := make(Changelog, len(b.cl))
+	copy(cl, b.cl)
+
+	if b.d.AttachKinds {
+		cl.attachKinds()
+	}
+
+	if b.d.ValueFormatter != nil {
+		cl.format(b.d.ValueFormatter)
+	}
+
+	return cl
+}
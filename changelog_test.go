@@ -0,0 +1,589 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/r3labs/diff/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestCompact(t *testing.T) {
+	cases := []struct {
+		Name      string
+		A, B      interface{}
+		Changelog diff.Changelog
+	}{
+		{
+			"contiguous-insert", []int{1, 2}, []int{1, 2, 3, 4, 5},
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"2"}, To: []int{3, 4, 5}},
+			},
+		},
+		{
+			"contiguous-delete", []string{"a", "b", "c", "d", "e"}, []string{"a", "e"},
+			diff.Changelog{
+				diff.Change{Type: diff.DELETE, Path: []string{"1"}, From: []string{"b", "c", "d"}},
+			},
+		},
+		{
+			"non-contiguous", []int{1, 2, 3, 4, 5}, []int{1, 3, 5},
+			diff.Changelog{
+				diff.Change{Type: diff.DELETE, Path: []string{"1"}, From: 2},
+				diff.Change{Type: diff.DELETE, Path: []string{"3"}, From: 4},
+			},
+		},
+		{
+			"mixed-types", []interface{}{1}, []interface{}{1, "a", 2},
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"1"}, To: []interface{}{"a", 2}},
+			},
+		},
+		{
+			"nested", map[string][]int{"a": {1}, "b": {1}}, map[string][]int{"a": {1, 2, 3}, "b": {1}},
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"a", "1"}, To: []int{2, 3}},
+			},
+		},
+		{
+			"struct-fields", tstruct{Name: "a", Value: 1}, tstruct{Name: "b", Value: 2},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"name"}, From: "a", To: "b"},
+				diff.Change{Type: diff.UPDATE, Path: []string{"value"}, From: 1, To: 2},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B)
+			require.Nil(t, err)
+
+			cl = cl.Compact()
+			require.Equal(t, len(tc.Changelog), len(cl))
+
+			for i, c := range cl {
+				assert.Equal(t, tc.Changelog[i].Type, c.Type)
+				assert.Equal(t, tc.Changelog[i].Path, c.Path)
+				assert.Equal(t, tc.Changelog[i].From, c.From)
+				assert.Equal(t, tc.Changelog[i].To, c.To)
+			}
+		})
+	}
+}
+
+func TestSquash(t *testing.T) {
+	cases := []struct {
+		Name      string
+		Changelog diff.Changelog
+		Expected  diff.Changelog
+	}{
+		{
+			"update-update",
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"name"}, From: "a", To: "b"},
+				diff.Change{Type: diff.UPDATE, Path: []string{"name"}, From: "b", To: "c"},
+			},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"name"}, From: "a", To: "c"},
+			},
+		},
+		{
+			"create-delete",
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"tags", "0"}, To: "x"},
+				diff.Change{Type: diff.DELETE, Path: []string{"tags", "0"}, From: "x"},
+			},
+			nil,
+		},
+		{
+			"delete-create",
+			diff.Changelog{
+				diff.Change{Type: diff.DELETE, Path: []string{"tags", "0"}, From: "x"},
+				diff.Change{Type: diff.CREATE, Path: []string{"tags", "0"}, To: "y"},
+			},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"tags", "0"}, From: "x", To: "y"},
+			},
+		},
+		{
+			"create-update",
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"id"}, To: 1},
+				diff.Change{Type: diff.UPDATE, Path: []string{"id"}, From: 1, To: 2},
+			},
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"id"}, To: 2},
+			},
+		},
+		{
+			"update-delete",
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"id"}, From: 1, To: 2},
+				diff.Change{Type: diff.DELETE, Path: []string{"id"}, From: 2},
+			},
+			diff.Changelog{
+				diff.Change{Type: diff.DELETE, Path: []string{"id"}, From: 1},
+			},
+		},
+		{
+			"no-op",
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"name"}, From: "a", To: "b"},
+				diff.Change{Type: diff.UPDATE, Path: []string{"name"}, From: "b", To: "a"},
+				diff.Change{Type: diff.DELETE, Path: []string{"id"}, From: 1},
+				diff.Change{Type: diff.CREATE, Path: []string{"id"}, To: 1},
+			},
+			nil,
+		},
+		{
+			"preserves-order",
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"b"}, From: 1, To: 2},
+				diff.Change{Type: diff.UPDATE, Path: []string{"a"}, From: 1, To: 2},
+				diff.Change{Type: diff.UPDATE, Path: []string{"b"}, From: 2, To: 3},
+				diff.Change{Type: diff.CREATE, Path: []string{"c"}, To: 1},
+			},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"b"}, From: 1, To: 3},
+				diff.Change{Type: diff.UPDATE, Path: []string{"a"}, From: 1, To: 2},
+				diff.Change{Type: diff.CREATE, Path: []string{"c"}, To: 1},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, tc.Changelog.Squash())
+		})
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	type key struct {
+		Name string `msgpack:"name"`
+	}
+
+	mk, err := msgpack.Marshal(key{Name: "k"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		Name     string
+		Change   diff.Change
+		Expected string
+	}{
+		{"create", diff.Change{Type: diff.CREATE, Path: []string{"tags", "0"}, To: "one"}, `CREATE tags.0: "one"`},
+		{"update", diff.Change{Type: diff.UPDATE, Path: []string{"name"}, From: "one", To: "two"}, `UPDATE name: "one" -> "two"`},
+		{"delete", diff.Change{Type: diff.DELETE, Path: []string{"value"}, From: 1}, `DELETE value: 1`},
+		{"map-key", diff.Change{Type: diff.UPDATE, Path: []string{"items", string(mk), "count"}, From: 1, To: nil}, `UPDATE items.map[name:k].count: 1 -> <nil>`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, tc.Change.String())
+		})
+	}
+
+	cl := diff.Changelog{cases[0].Change, cases[1].Change}
+	assert.Equal(t, "CREATE tags.0: \"one\"\nUPDATE name: \"one\" -> \"two\"", cl.String())
+}
+
+func TestGroupByPathPrefix(t *testing.T) {
+	cl := diff.Changelog{
+		diff.Change{Type: diff.UPDATE, Path: []string{"name"}, From: "a", To: "b"},
+		diff.Change{Type: diff.CREATE, Path: []string{"tags", "0"}, To: "x"},
+		diff.Change{Type: diff.UPDATE, Path: []string{"tags", "1"}, From: "y", To: "z"},
+		diff.Change{Type: diff.DELETE, Path: []string{"tags", "2"}, From: "w"},
+		diff.Change{Type: diff.UPDATE, Path: []string{"nutrients", "vitamins", "0"}, From: "c", To: "d"},
+		diff.Change{Type: diff.CREATE, Path: []string{"nutrients", "vitamins", "1"}, To: "e"},
+		diff.Change{Type: diff.UPDATE, Path: []string{"nutrients", "minerals", "0"}, From: "iron", To: "zinc"},
+	}
+
+	t.Run("depth-1", func(t *testing.T) {
+		groups := cl.GroupByPathPrefix(1)
+		require.Len(t, groups, 3)
+		assert.Equal(t, cl[:1], groups["name"])
+		assert.Equal(t, cl[1:4], groups["tags"])
+		assert.Equal(t, cl[4:], groups["nutrients"])
+	})
+
+	t.Run("depth-2", func(t *testing.T) {
+		groups := cl.GroupByPathPrefix(2)
+		require.Len(t, groups, 6)
+		assert.Equal(t, cl[:1], groups["name"])
+		assert.Equal(t, cl[1:2], groups["tags.0"])
+		assert.Equal(t, cl[4:6], groups["nutrients.vitamins"])
+		assert.Equal(t, cl[6:], groups["nutrients.minerals"])
+	})
+}
+
+func TestNestByComparative(t *testing.T) {
+	type size struct {
+		Width int `diff:"width"`
+	}
+
+	type item struct {
+		ID    string `diff:"id,identifier"`
+		Name  string `diff:"name"`
+		Price int    `diff:"price"`
+		Stock int    `diff:"stock"`
+		Size  size   `diff:"size"`
+	}
+
+	type order struct {
+		Items []item            `diff:"items"`
+		Notes map[string]string `diff:"notes"`
+		Codes []string          `diff:"codes"`
+	}
+
+	a := order{
+		Items: []item{{"a", "apple", 1, 10, size{1}}, {"b", "banana", 2, 20, size{2}}, {"c", "cherry", 3, 30, size{3}}},
+		Notes: map[string]string{"gift": "no"},
+		Codes: []string{"x"},
+	}
+	b := order{
+		Items: []item{{"c", "cherry", 4, 30, size{3}}, {"a", "apricot", 5, 9, size{7}}, {"d", "date", 6, 60, size{6}}},
+		Notes: map[string]string{"gift": "yes"},
+		Codes: []string{"y"},
+	}
+
+	cl, err := diff.Diff(a, b, diff.TrackComparativeIndices(true), diff.DiscardComplexOrigin())
+	require.NoError(t, err)
+
+	groups := cl.NestByComparative()
+	require.Len(t, groups, 4)
+
+	paths := func(cl diff.Changelog) [][]string {
+		var p [][]string
+		for _, c := range cl {
+			p = append(p, c.Path)
+		}
+		return p
+	}
+
+	assert.Equal(t, [][]string{{"name"}, {"price"}, {"stock"}, {"size", "width"}}, paths(groups["items.a"]))
+	assert.Equal(t, "apple", groups["items.a"][0].From)
+	assert.Equal(t, "apricot", groups["items.a"][0].To)
+
+	assert.Equal(t, [][]string{{"price"}}, paths(groups["items.c"]))
+
+	assert.Len(t, groups["items.b"], 5)
+	assert.Len(t, groups["items.d"], 5)
+	for _, c := range groups["items.d"] {
+		assert.Equal(t, diff.CREATE, c.Type)
+	}
+
+	// changes to maps and unidentified slices don't belong to an element
+	assert.NotContains(t, groups, "notes")
+	assert.NotContains(t, groups, "codes")
+
+	// elements are only recorded when tracking the indices of identified elements
+	cl, err = diff.Diff(a, b)
+	require.NoError(t, err)
+	assert.Empty(t, cl.NestByComparative())
+}
+
+func TestChangelogBuilder(t *testing.T) {
+	type key struct {
+		Region string
+		Zone   int
+	}
+
+	type doc struct {
+		Name  string         `diff:"name"`
+		Tags  []string       `diff:"tags"`
+		Zones map[key]string `diff:"zones"`
+	}
+
+	a := doc{Name: "one", Tags: []string{"a"}, Zones: map[key]string{{"eu", 1}: "x", {"us", 2}: "y"}}
+	b := doc{Name: "two", Tags: []string{"a", "b"}, Zones: map[key]string{{"eu", 1}: "z", {"ap", 3}: "w"}}
+
+	cb, err := diff.NewChangelogBuilder(diff.StructMapKeySupport())
+	require.Nil(t, err)
+
+	cl := cb.
+		Update([]string{"name"}, "one", "two").
+		Create([]string{"tags", "1"}, "b").
+		Delete([]string{"zones", cb.Key(key{"us", 2})}, "y").
+		Update([]string{"zones", cb.Key(key{"eu", 1})}, "x", "z").
+		Create([]string{"zones", cb.Key(key{"ap", 3})}, "w").
+		Build()
+
+	expected, err := diff.Diff(a, b, diff.StructMapKeySupport(), diff.DiscardComplexOrigin())
+	require.Nil(t, err)
+	assert.ElementsMatch(t, expected, cl)
+
+	d, err := diff.NewDiffer(diff.StructMapKeySupport())
+	require.Nil(t, err)
+
+	pl := d.Patch(cl, &a)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, b, a)
+
+	_, err = diff.NewChangelogBuilder(diff.ChunkedSlices(0))
+	assert.NotNil(t, err)
+}
+
+func TestClassify(t *testing.T) {
+	type server struct {
+		ID     string            `diff:"id,immutable"`
+		Name   string            `diff:"name"`
+		Labels map[string]string `diff:"labels"`
+		Owners []string          `diff:"owners,immutable"`
+	}
+
+	cl := diff.Changelog{
+		{Type: diff.CREATE, Path: []string{"labels", "env"}, To: "prod"},
+		{Type: diff.UPDATE, Path: []string{"name"}, From: "a", To: "b"},
+		{Type: diff.DELETE, Path: []string{"labels", "team"}, From: "core"},
+		{Type: diff.UPDATE, Path: []string{"id"}, From: "1", To: "2"},
+		{Type: diff.CREATE, Path: []string{"owners", "1"}, To: "bob"},
+	}
+
+	assert.Equal(t, map[string]diff.Changelog{
+		diff.SAFE:      {cl[0], cl[4]},
+		diff.REVIEW:    {cl[1], cl[3]},
+		diff.DANGEROUS: {cl[2]},
+	}, cl.Classify())
+
+	d, err := diff.NewDiffer()
+	require.Nil(t, err)
+
+	assert.Equal(t, map[string]diff.Changelog{
+		diff.SAFE:      {cl[0]},
+		diff.REVIEW:    {cl[1]},
+		diff.DANGEROUS: {cl[2], cl[3], cl[4]},
+	}, d.Classify(cl, &server{}))
+}
+
+func TestPaths(t *testing.T) {
+	type key struct {
+		Region string
+	}
+
+	type doc struct {
+		Name   string            `diff:"name"`
+		Tags   []string          `diff:"tags"`
+		Labels map[string]string `diff:"labels"`
+	}
+
+	a := doc{Name: "a", Tags: []string{"x"}, Labels: map[string]string{"a": "1"}}
+	b := doc{Name: "b", Tags: []string{"y", "z"}, Labels: map[string]string{"a": "2", "b": "3"}}
+
+	cl, err := diff.Diff(a, b)
+	require.Nil(t, err)
+
+	cl = append(cl, diff.Change{Type: diff.UPDATE, Path: []string{"name"}, From: "b", To: "c"})
+
+	assert.Equal(t, [][]string{
+		{"name"},
+		{"tags", "0"},
+		{"tags", "1"},
+		{"labels", "a"},
+		{"labels", "b"},
+	}, cl.Paths())
+
+	assert.Equal(t, []string{"name", "tags", "labels"}, cl.TopLevelFields())
+
+	cl, err = diff.Diff(map[key]int{{"eu"}: 1, {"us"}: 2}, map[key]int{{"eu"}: 2}, diff.StructMapKeySupport())
+	require.Nil(t, err)
+	assert.Equal(t, []string{"map[Region:eu]", "map[Region:us]"}, cl.TopLevelFields())
+
+	assert.Nil(t, diff.Changelog{}.Paths())
+	assert.Nil(t, diff.Changelog{}.TopLevelFields())
+}
+
+func TestClean(t *testing.T) {
+	cl := diff.Changelog{
+		{Type: diff.UPDATE, Path: []string{"name"}, From: "a", To: "b"},
+		{Type: diff.UPDATE, Path: []string{"count"}, From: 1, To: 1},
+		{Type: diff.EQUAL, Path: []string{"size"}, From: 2, To: 2},
+		{Type: diff.UPDATE, Path: []string{"name"}, From: "a", To: "b"},
+		{Type: diff.CREATE, Path: []string{}, From: nil, To: nil},
+		{Type: diff.CREATE, Path: []string{"tags", "0"}, To: "x"},
+		{Type: diff.DELETE, Path: []string{"tags", "0"}, From: "x"},
+		{Type: diff.UPDATE, Path: []string{"name"}, From: "b", To: "c"},
+		{Type: diff.UPDATE, Path: []string{}, From: 1, To: 2},
+		{Type: diff.CREATE, Path: []string{"tags", "0"}, To: "x"},
+		{Type: diff.UPDATE, Path: []string{"items"}, From: []int{1}, To: []int{1}},
+	}
+
+	assert.Equal(t, diff.Changelog{
+		{Type: diff.UPDATE, Path: []string{"name"}, From: "a", To: "b"},
+		{Type: diff.CREATE, Path: []string{"tags", "0"}, To: "x"},
+		{Type: diff.DELETE, Path: []string{"tags", "0"}, From: "x"},
+		{Type: diff.UPDATE, Path: []string{"name"}, From: "b", To: "c"},
+		{Type: diff.UPDATE, Path: []string{}, From: 1, To: 2},
+		{Type: diff.CREATE, Path: []string{"tags", "0"}, To: "x"},
+	}, cl.Clean())
+
+	assert.Nil(t, diff.Changelog{}.Clean())
+}
+
+func TestStats(t *testing.T) {
+	cl := diff.Changelog{
+		{Type: diff.CREATE, Path: []string{"tags", "1"}, To: "b"},
+		{Type: diff.UPDATE, Path: []string{"name"}, From: "a", To: "b"},
+		{Type: diff.UPDATE, Path: []string{"count"}, From: 1, To: 2},
+		{Type: diff.DELETE, Path: []string{"size"}, From: 3},
+		{Type: diff.EQUAL, Path: []string{"level"}, From: 1, To: 1},
+		{Type: diff.WARNING, Path: []string{"items"}, To: "invalid json"},
+	}
+
+	s := cl.Stats()
+	assert.Equal(t, diff.DiffStats{Creates: 1, Updates: 2, Deletes: 1}, s)
+	assert.Equal(t, 4, s.Total())
+
+	assert.Equal(t, 0, diff.Changelog{}.Stats().Total())
+}
+
+func TestChangelogSort(t *testing.T) {
+	sorted := diff.Changelog{
+		{Type: diff.UPDATE, Path: []string{}, From: 1, To: 2},
+		{Type: diff.UPDATE, Path: []string{"a"}, From: "x", To: "y"},
+		{Type: diff.CREATE, Path: []string{"a", "b"}, To: 1},
+		{Type: diff.CREATE, Path: []string{"a", "b"}, To: 2},
+		{Type: diff.DELETE, Path: []string{"a", "b"}, From: 1},
+		{Type: diff.CREATE, Path: []string{"a", "c"}, To: 3},
+		{Type: diff.UPDATE, Path: []string{"ab"}, From: 1, To: 2},
+		{Type: diff.UPDATE, Path: []string{"b", "a"}, From: 1, To: 2},
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 20; n++ {
+		cl := make(diff.Changelog, len(sorted))
+		for i, j := range r.Perm(len(sorted)) {
+			cl[i] = sorted[j]
+		}
+
+		cl.Sort()
+		assert.Equal(t, sorted, cl)
+	}
+
+	type doc struct {
+		Name string            `diff:"name"`
+		Tags []string          `diff:"tags"`
+		Meta map[string]string `diff:"meta"`
+	}
+
+	a := doc{Name: "a", Tags: []string{"x", "y"}, Meta: map[string]string{"env": "dev"}}
+	b := doc{Name: "b", Tags: []string{"x"}, Meta: map[string]string{"team": "core"}}
+
+	// the changes of a diff and those of its reverse, reordered, sort to the same sequence
+	cl, err := diff.Diff(a, b)
+	require.NoError(t, err)
+
+	reversed, err := diff.Diff(b, a)
+	require.NoError(t, err)
+	reversed = reversed.Reverse()
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+
+	cl.Sort()
+	reversed.Sort()
+	require.Len(t, reversed, len(cl))
+	for i := range cl {
+		assert.Equal(t, cl[i].Type, reversed[i].Type)
+		assert.Equal(t, cl[i].Path, reversed[i].Path)
+		assert.Equal(t, cl[i].From, reversed[i].From)
+		assert.Equal(t, cl[i].To, reversed[i].To)
+	}
+}
+
+func TestChangelogMsgpack(t *testing.T) {
+	type address struct {
+		City string `diff:"city"`
+		Zip  int    `diff:"zip"`
+	}
+	type account struct {
+		Name    string      `diff:"name"`
+		Age     int         `diff:"age"`
+		Level   uint8       `diff:"level"`
+		Address interface{} `diff:"address"`
+	}
+
+	diff.RegisterType("changelog_test.address", address{})
+	diff.RegisterType("changelog_test.account", account{})
+
+	a := account{Name: "a", Age: 1, Level: 2, Address: address{City: "Paris", Zip: 1}}
+	b := account{Name: "b", Age: 300, Level: 3, Address: nil}
+
+	cl, err := diff.Diff(a, b, diff.AttachKinds(true))
+	require.NoError(t, err)
+	require.Len(t, cl, 4)
+
+	data, err := cl.MarshalMsgpack()
+	require.NoError(t, err)
+
+	rcl, err := diff.UnmarshalChangelog(data)
+	require.NoError(t, err)
+
+	// values keep their concrete types
+	require.Len(t, rcl, len(cl))
+	for i := range cl {
+		assert.Equal(t, cl[i].Type, rcl[i].Type)
+		assert.Equal(t, cl[i].Path, rcl[i].Path)
+		assert.Equal(t, cl[i].From, rcl[i].From)
+		assert.Equal(t, cl[i].To, rcl[i].To)
+		assert.Equal(t, cl[i].FromKind, rcl[i].FromKind)
+		assert.Equal(t, cl[i].ToKind, rcl[i].ToKind)
+	}
+	assert.Equal(t, address{City: "Paris", Zip: 1}, rcl[3].From)
+
+	target := a
+	pl := diff.Patch(rcl, &target)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, b, target)
+
+	// the changelog is also encoded as a whole by msgpack itself
+	data, err = msgpack.Marshal(cl)
+	require.NoError(t, err)
+
+	var mcl diff.Changelog
+	require.NoError(t, msgpack.Unmarshal(data, &mcl))
+	assert.Equal(t, rcl, mcl)
+
+	// the parent of each change is kept as well
+	cl, err = diff.Diff(address{City: "Paris", Zip: 1}, address{City: "Lyon", Zip: 2})
+	require.NoError(t, err)
+
+	data, err = cl.MarshalMsgpack()
+	require.NoError(t, err)
+
+	rcl, err = diff.UnmarshalChangelog(data)
+	require.NoError(t, err)
+	assert.Equal(t, cl, rcl)
+
+	// values of unregistered types are decoded loosely
+	idx := func(i int) *int { return &i }
+
+	type point struct {
+		X, Y int
+	}
+
+	cl = diff.Changelog{
+		{Type: diff.UPDATE, Path: []string{"value"}, From: point{1, 2}, To: point{3, 4}, FromIndex: idx(1)},
+		{Type: diff.CREATE, Path: []string{"items", "0"}, To: []string{"x"}},
+	}
+
+	data, err = cl.MarshalMsgpack()
+	require.NoError(t, err)
+
+	rcl, err = diff.UnmarshalChangelog(data)
+	require.NoError(t, err)
+	require.Len(t, rcl, 2)
+	assert.Equal(t, map[string]interface{}{"X": int8(1), "Y": int8(2)}, rcl[0].From)
+	assert.Equal(t, idx(1), rcl[0].FromIndex)
+	assert.Equal(t, []interface{}{"x"}, rcl[1].To)
+	assert.Nil(t, rcl[1].From)
+
+	_, err = diff.UnmarshalChangelog([]byte("invalid"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,85 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import "reflect"
+
+const (
+	// SAFE changes only add values, or have no effect, and can be applied automatically
+	SAFE = "safe"
+	// REVIEW changes replace values and should be reviewed before being applied
+	REVIEW = "review"
+	// DANGEROUS changes remove values, or modify immutable ones
+	DANGEROUS = "dangerous"
+)
+
+// Classify buckets the changes by the risk of applying them. Creates are safe, updates and warnings
+// need review and deletes are dangerous
+func (cl Changelog) Classify() map[string]Changelog {
+	groups := make(map[string]Changelog)
+
+	for _, c := range cl {
+		r := risk(c)
+		groups[r] = append(groups[r], c)
+	}
+
+	return groups
+}
+
+// Classify buckets the changes by risk like Changelog.Classify. Changes to fields of v that are
+// tagged as immutable, or to values held by them, are always dangerous
+func (d *Differ) Classify(cl Changelog, v interface{}) map[string]Changelog {
+	groups := make(map[string]Changelog)
+	t := reflect.TypeOf(v)
+
+	for _, c := range cl {
+		r := risk(c)
+		if t != nil && d.immutablePath(t, c.Path) {
+			r = DANGEROUS
+		}
+		groups[r] = append(groups[r], c)
+	}
+
+	return groups
+}
+
+func risk(c Change) string {
+	switch c.Type {
+	case CREATE, EQUAL:
+		return SAFE
+	case DELETE:
+		return DANGEROUS
+	}
+
+	return REVIEW
+}
+
+// immutablePath returns true if the path passes through a struct field tagged as immutable
+func (d *Differ) immutablePath(t reflect.Type, path []string) bool {
+	for len(path) > 0 {
+		switch t.Kind() {
+		case reflect.Ptr:
+			t = t.Elem()
+			continue
+		case reflect.Map, reflect.Slice, reflect.Array:
+			t = t.Elem()
+		case reflect.Struct:
+			f, ok := d.pathField(t, path[0])
+			if !ok {
+				return false
+			}
+			if hasTagOption(d.TagName, f, "immutable") {
+				return true
+			}
+			t = f.Type
+		default:
+			return false
+		}
+
+		path = path[1:]
+	}
+
+	return false
+}
@@ -11,12 +11,16 @@ import (
 // Comparative ...
 type Comparative struct {
 	A, B *reflect.Value
+
+	// positions of the values in the slices they were collected from
+	ai, bi *int
 }
 
 // ComparativeList : stores indexed comparative
 type ComparativeList struct {
-	m    map[interface{}]*Comparative
-	keys []interface{}
+	m     map[interface{}]*Comparative
+	keys  []interface{}
+	equal func(a, b interface{}) bool
 }
 
 // NewComparativeList : returns a new comparative list
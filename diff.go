@@ -5,11 +5,13 @@
 package diff
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/vmihailenco/msgpack/v5"
 )
@@ -21,8 +23,23 @@ const (
 	UPDATE = "update"
 	// DELETE represents when an element has been removed
 	DELETE = "delete"
+	// EQUAL represents when an element is unchanged. Only reported when IncludeUnchanged is enabled
+	EQUAL = "equal"
+	// WARNING represents a possible problem found while diffing, such as a changed identifier or
+	// invalid json. Warnings are ignored by Patch
+	WARNING = "warning"
+	// END represents the end of a streamed diff, holding a StreamSummary in To. Only emitted by
+	// DiffStream when EmitEndMarker is enabled. End markers are ignored by Patch
+	END = "end"
 )
 
+// Redacted is the type of RedactedValue, which tells masked values apart from actual ones
+type Redacted string
+
+// RedactedValue replaces the values of fields tagged with the redact option. As the actual values
+// are unknown, Patch ignores changes that hold it
+const RedactedValue Redacted = "***"
+
 // DiffType represents an enum with all the supported diff types
 type DiffType uint8
 
@@ -39,6 +56,7 @@ const (
 	MAP
 	PTR
 	INTERFACE
+	FUNC
 )
 
 func (t DiffType) String() string {
@@ -65,6 +83,8 @@ func (t DiffType) String() string {
 		return "PTR"
 	case INTERFACE:
 		return "INTERFACE"
+	case FUNC:
+		return "FUNC"
 	default:
 		return "UNSUPPORTED"
 	}
@@ -75,17 +95,63 @@ type DiffFunc func([]string, reflect.Value, reflect.Value, interface{}) error
 
 // Differ a configurable diff instance
 type Differ struct {
-	TagName                string
-	SliceOrdering          bool
-	DisableStructValues    bool
-	customValueDiffers     []ValueDiffer
-	cl                     Changelog
-	AllowTypeMismatch      bool
-	DiscardParent          bool
-	StructMapKeys          bool
-	FlattenEmbeddedStructs bool
-	ConvertCompatibleTypes bool
-	Filter                 FilterFunc
+	TagName                 string
+	SliceOrdering           bool
+	DisableStructValues     bool
+	customValueDiffers      []ValueDiffer
+	cl                      Changelog
+	AllowTypeMismatch       bool
+	DiscardParent           bool
+	StructMapKeys           bool
+	FlattenEmbeddedStructs  bool
+	ConvertCompatibleTypes  bool
+	Filter                  FilterFunc
+	IgnoreTimeFields        bool
+	ReorderAsWhole          bool
+	IncludeUnchanged        bool
+	HexEncodeBytes          bool
+	MapDefault              MapDefaultFunc
+	UntaggedStructsAtomic   bool
+	SliceLCS                bool
+	CompareFuncByPointer    bool
+	MapValueEqual           func(a, b interface{}) bool
+	OmitEmpty               bool
+	IgnorePaths             [][]string
+	AtomicTypes             []reflect.Type
+	TrackComparativeIndices bool
+	stringSynonyms          map[string]int
+	ChunkSize               int
+	MaxValueBytes           int
+	WarnOnIdentifierChange  bool
+	SemanticJSON            bool
+	MapValueKey             func(value interface{}) interface{}
+	TagFallback             []string
+	StructWholeThreshold    float64
+	NumericEquality         bool
+	TrustHash               bool
+	ValueFormatter          func(v interface{}) string
+	PositionalStructSlices  bool
+	orderedMaps             map[reflect.Type]OrderedMapFunc
+	namedDiffers            map[string]ValueDiffer
+	comparators             map[string]func(a, b interface{}) bool
+	computedFields          []computedField
+	EqualityMethod          string
+	ReversePerspective      bool
+	OnlyPaths               [][]string
+	MapSeparator            string
+	AttachKinds             bool
+	DereferencePointers     bool
+	IgnoreUnsupported       bool
+	FuzzySliceThreshold     float64
+	NilEqualsZeroStruct     bool
+	OmitZeroCreates         bool
+	EmitEndMarker           bool
+	IncludeUnexported       bool
+	TypedValues             bool
+	ctx                     context.Context
+	emit                    func(Change) error
+	held                    int
+	visiting                map[visit]bool
 }
 
 // Changelog stores a list of changed items
@@ -98,6 +164,32 @@ type Change struct {
 	From   interface{} `json:"from"`
 	To     interface{} `json:"to"`
 	parent interface{} `json:"parent"`
+
+	// FromIndex and ToIndex hold the positions of an identified slice element that the
+	// change belongs to, when enabled with TrackComparativeIndices
+	FromIndex *int `json:"fromIndex,omitempty"`
+	ToIndex   *int `json:"toIndex,omitempty"`
+
+	// FromFormatted and ToFormatted hold the values as rendered by a ValueFormatter
+	FromFormatted string `json:"fromFormatted,omitempty"`
+	ToFormatted   string `json:"toFormatted,omitempty"`
+
+	// FromKind and ToKind hold the reflect.Kind names of the values, when enabled with AttachKinds
+	FromKind string `json:"fromKind,omitempty"`
+	ToKind   string `json:"toKind,omitempty"`
+
+	// FromType and ToType hold the names the types of the values were registered under with
+	// RegisterType, when enabled with TypedValues
+	FromType string `json:"fromType,omitempty"`
+	ToType   string `json:"toType,omitempty"`
+
+	// Redacted is set when the values of the change were masked with RedactedValue. Patch ignores
+	// redacted changes, as their actual values are unknown
+	Redacted bool `json:"redacted,omitempty"`
+
+	// element is the length of the path up to and including the identifier of the slice element
+	// the change belongs to, recorded along with FromIndex and ToIndex
+	element int
 }
 
 // ValueDiffer is an interface for custom differs
@@ -107,6 +199,30 @@ type ValueDiffer interface {
 	InsertParentDiffer(dfunc func(path []string, a, b reflect.Value, p interface{}) error)
 }
 
+// Comparable can be implemented by types whose semantic equality differs from reflect.DeepEqual.
+// When implemented, DiffEqual decides whether a single update is emitted for the value
+type Comparable interface {
+	DiffEqual(other interface{}) bool
+}
+
+var comparableType = reflect.TypeOf((*Comparable)(nil)).Elem()
+
+// DiffIgnored can be implemented by types that should be excluded from diffing based on their state.
+// Struct fields holding a value for which DiffIgnore returns true are skipped
+type DiffIgnored interface {
+	DiffIgnore() bool
+}
+
+var diffIgnoredType = reflect.TypeOf((*DiffIgnored)(nil)).Elem()
+
+// Hasher can be implemented by types that can cheaply summarise their contents. With TrustHash
+// enabled, values with equal hashes are treated as unchanged without being diffed
+type Hasher interface {
+	Hash() uint64
+}
+
+var hasherType = reflect.TypeOf((*Hasher)(nil)).Elem()
+
 // Changed returns true if both values differ
 func Changed(a, b interface{}) bool {
 	cl, _ := Diff(a, b)
@@ -144,6 +260,10 @@ func NewDiffer(opts ...func(d *Differ) error) (*Differ, error) {
 // is the path to the field from the root of the diff.
 type FilterFunc func(path []string, parent reflect.Type, field reflect.StructField) bool
 
+// MapDefaultFunc returns the default value for a map key, and whether the key has a default.
+// A key missing from one map is compared against its default, rather than reported as created or deleted
+type MapDefaultFunc func(key interface{}) (interface{}, bool)
+
 // StructValues gets all values from a struct
 // values are stored as "created" or "deleted" entries in the changelog,
 // depending on the change type specified
@@ -184,6 +304,34 @@ func (cl *Changelog) Filter(path []string) Changelog {
 	return ncl
 }
 
+// FilterFunc filter changes based on an arbitrary predicate. Only changes for which f returns true are kept
+func (cl Changelog) FilterFunc(f func(Change) bool) Changelog {
+	var ncl Changelog
+
+	for _, c := range cl {
+		if f(c) {
+			ncl = append(ncl, c)
+		}
+	}
+
+	return ncl
+}
+
+// RewritePaths replaces the path prefix 'from' with 'to' on every change whose path begins with 'from'.
+// Changes that do not match are returned unmodified
+func (cl Changelog) RewritePaths(from, to []string) Changelog {
+	ncl := make(Changelog, len(cl))
+
+	for i, c := range cl {
+		if Path(c.Path).HasPrefix(from) {
+			c.Path = copyAppend(to, c.Path[len(from):]...)
+		}
+		ncl[i] = c
+	}
+
+	return ncl
+}
+
 func (d *Differ) getDiffType(a, b reflect.Value) (DiffType, DiffFunc) {
 	switch {
 	case are(a, b, reflect.Struct, reflect.Invalid):
@@ -208,6 +356,8 @@ func (d *Differ) getDiffType(a, b reflect.Value) (DiffType, DiffFunc) {
 		return PTR, d.diffPtr
 	case are(a, b, reflect.Interface, reflect.Invalid):
 		return INTERFACE, d.diffInterface
+	case are(a, b, reflect.Func, reflect.Invalid) && d.CompareFuncByPointer:
+		return FUNC, d.diffFunc
 	default:
 		return UNSUPPORTED, nil
 	}
@@ -215,13 +365,89 @@ func (d *Differ) getDiffType(a, b reflect.Value) (DiffType, DiffFunc) {
 
 // Diff returns a changelog of all mutated values from both
 func (d *Differ) Diff(a, b interface{}) (Changelog, error) {
+	return d.DiffContext(context.Background(), a, b)
+}
+
+// DiffContext returns a changelog of all mutated values from both. The diff is aborted,
+// returning the context's error, as soon as the context is cancelled
+func (d *Differ) DiffContext(ctx context.Context, a, b interface{}) (Changelog, error) {
 	// reset the state of the diff
 	d.cl = Changelog{}
+	d.ctx = ctx
+	defer func() { d.ctx = nil }()
+
+	if d.ReversePerspective {
+		a, b = b, a
+	}
 
-	return d.cl, d.diff([]string{}, reflect.ValueOf(a), reflect.ValueOf(b), nil)
+	err := d.diff([]string{}, reflect.ValueOf(a), reflect.ValueOf(b), nil)
+
+	d.finish(d.cl)
+
+	return d.cl, err
 }
 
-func (d *Differ) diff(path []string, a, b reflect.Value, parent interface{}) error {
+// finish applies the options that operate on complete changes
+func (d *Differ) finish(cl Changelog) {
+	if d.AttachKinds {
+		cl.attachKinds()
+	}
+
+	if d.MaxValueBytes > 0 {
+		cl.truncate(d.MaxValueBytes)
+	}
+
+	// types are recorded once the values are final
+	if d.TypedValues {
+		cl.attachTypes()
+	}
+
+	if d.ValueFormatter != nil {
+		cl.format(d.ValueFormatter)
+	}
+}
+
+func (d *Differ) diff(path []string, a, b reflect.Value, parent interface{}) (err error) {
+	// stop as soon as the diff has been cancelled
+	if d.ctx != nil {
+		if err := d.ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	// when streaming, changes are emitted once nothing can alter them anymore
+	if d.emit != nil && d.held == 0 {
+		defer func() {
+			if err == nil {
+				err = d.flush()
+			}
+		}()
+	}
+
+	// prune ignored subtrees before descending into them
+	for _, ip := range d.IgnorePaths {
+		if pathmatch(ip, path) {
+			return nil
+		}
+	}
+
+	if len(d.OnlyPaths) > 0 && !onlyPath(d.OnlyPaths, path) {
+		return nil
+	}
+
+	// values that refer back to themselves are only compared once
+	if v, ok := visitOf(a, b); ok {
+		if d.visiting[v] {
+			return nil
+		}
+
+		if d.visiting == nil {
+			d.visiting = make(map[visit]bool)
+		}
+
+		d.visiting[v] = true
+		defer delete(d.visiting, v)
+	}
 
 	//look and see if we need to discard the parent
 	if parent != nil {
@@ -230,8 +456,24 @@ func (d *Differ) diff(path []string, a, b reflect.Value, parent interface{}) err
 		}
 	}
 
+	// skip time values entirely if requested
+	if d.IgnoreTimeFields && (isTime(a) || isTime(b)) {
+		return nil
+	}
+
+	// pointers are compared with the values of their own type they point to
+	if d.DereferencePointers {
+		if da, db, ok := dereference(a, b); ok {
+			return d.diffDereferenced(path, da, db, parent)
+		}
+	}
+
 	// check if types match or are
 	if invalid(a, b) {
+		if d.NumericEquality && numericEqual(a, b) {
+			d.unchanged(path, a, b, parent)
+			return nil
+		}
 		if d.AllowTypeMismatch {
 			d.cl.Add(UPDATE, path, a.Interface(), b.Interface())
 			return nil
@@ -239,6 +481,42 @@ func (d *Differ) diff(path []string, a, b reflect.Value, parent interface{}) err
 		return ErrTypeMismatch
 	}
 
+	// types marked as atomic are compared as a whole, without descending into them
+	if d.atomic(a) || d.atomic(b) {
+		return d.diffAtomic(path, a, b, parent)
+	}
+
+	// arbitrary precision numbers are compared by their value
+	if isBig(a, b) {
+		return d.diffBig(path, a, b, parent)
+	}
+
+	// ip addresses and networks are compared by their value
+	if isIP(a, b) {
+		return d.diffIP(path, a, b, parent)
+	}
+
+	// values with equal hashes are trusted to be unchanged
+	if d.TrustHash && sameHash(a, b) {
+		d.unchanged(path, a, b, parent)
+		return nil
+	}
+
+	// types registered as ordered maps are compared by their keys
+	if f, ok := d.orderedMap(a, b); ok {
+		return d.diffOrderedMap(path, a, b, f, parent)
+	}
+
+	// types with the configured equality method are compared as a whole
+	if d.EqualityMethod != "" && a.IsValid() && b.IsValid() && a.Type() == b.Type() && hasEqualityMethod(a.Type(), d.EqualityMethod) {
+		return d.diffEqualityMethod(path, a, b, parent)
+	}
+
+	// types that implement their own equality are compared as a whole
+	if a.IsValid() && b.IsValid() && a.Type() == b.Type() && isComparable(a.Type()) {
+		return d.diffComparable(path, a, b, parent)
+	}
+
 	// get the diff type and the corresponding built-int diff function to handle this type
 	diffType, diffFunc := d.getDiffType(a, b)
 
@@ -257,6 +535,10 @@ func (d *Differ) diff(path []string, a, b reflect.Value, parent interface{}) err
 
 	// then built-in diff functions
 	if diffType == UNSUPPORTED {
+		// channels hold no state that can be compared, so are always skipped
+		if d.IgnoreUnsupported || are(a, b, reflect.Chan, reflect.Invalid) {
+			return nil
+		}
 		return errors.New("unsupported type: " + a.Kind().String())
 	}
 
@@ -276,7 +558,16 @@ func (cl *Changelog) Add(t string, path []string, ftco ...interface{}) {
 	(*cl) = append((*cl), change)
 }
 
-func tagName(tag string, f reflect.StructField) string {
+// unchanged records an equal value when unchanged values are to be included in the changelog
+func (d *Differ) unchanged(path []string, a, b reflect.Value, parent interface{}) {
+	if d.IncludeUnchanged {
+		d.cl.Add(EQUAL, path, exportInterface(a), exportInterface(b), parent)
+	}
+}
+
+// tagName returns the name given to a field by its tag. When the tag doesn't name the
+// field, the fallback tags are tried in order
+func tagName(tag string, f reflect.StructField, fallbacks ...string) string {
 	t := f.Tag.Get(tag)
 
 	parts := strings.Split(t, ",")
@@ -284,21 +575,57 @@ func tagName(tag string, f reflect.StructField) string {
 		return "-"
 	}
 
+	if parts[0] == "" && len(fallbacks) > 0 {
+		return tagName(fallbacks[0], f, fallbacks[1:]...)
+	}
+
 	return parts[0]
 }
 
+// identifier returns the value of the field tagged as identifier. Elements identified by several
+// fields together get a compositeID of their values, in field order
 func identifier(tag string, v reflect.Value) interface{} {
 	if v.Kind() != reflect.Struct {
 		return nil
 	}
 
+	var ids []interface{}
+
 	for i := 0; i < v.NumField(); i++ {
 		if hasTagOption(tag, v.Type().Field(i), "identifier") {
-			return v.Field(i).Interface()
+			ids = append(ids, v.Field(i).Interface())
 		}
 	}
 
-	return nil
+	switch len(ids) {
+	case 0:
+		return nil
+	case 1:
+		return ids[0]
+	}
+
+	return newCompositeID(ids)
+}
+
+// compositeID identifies an element by the values of several fields. The key is unique for each
+// combination of values, while the text is used as the element's path segment
+type compositeID struct {
+	key  string
+	text string
+}
+
+func newCompositeID(ids []interface{}) compositeID {
+	b, err := msgpack.Marshal(ids)
+	if err != nil {
+		panic(err)
+	}
+
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = idstring(id)
+	}
+
+	return compositeID{key: string(b), text: strings.Join(parts, ",")}
 }
 
 func hasTagOption(tag string, f reflect.StructField, opt string) bool {
@@ -316,6 +643,22 @@ func hasTagOption(tag string, f reflect.StructField, opt string) bool {
 	return false
 }
 
+// tagOptionValue returns the value of a tag option given as opt=value
+func tagOptionValue(tag string, f reflect.StructField, opt string) (string, bool) {
+	parts := strings.Split(f.Tag.Get(tag), ",")
+	if len(parts) < 2 {
+		return "", false
+	}
+
+	for _, option := range parts[1:] {
+		if strings.HasPrefix(option, opt+"=") {
+			return strings.TrimPrefix(option, opt+"="), true
+		}
+	}
+
+	return "", false
+}
+
 func swapChange(t string, c Change) Change {
 	nc := Change{
 		Type: t,
@@ -338,6 +681,8 @@ func idComplex(v interface{}) string {
 		return v
 	case int:
 		return strconv.Itoa(v)
+	case compositeID:
+		return v.key
 	default:
 		b, err := msgpack.Marshal(v)
 		if err != nil {
@@ -353,6 +698,8 @@ func idstring(v interface{}) string {
 		return v
 	case int:
 		return strconv.Itoa(v)
+	case compositeID:
+		return v.text
 	default:
 		return fmt.Sprint(v)
 	}
@@ -407,6 +754,41 @@ func AreType(a, b reflect.Value, types ...reflect.Type) bool {
 	return amatch && bmatch
 }
 
+// sameHash returns true if both values implement Hasher and have the same hash
+func sameHash(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() || a.Type() != b.Type() || !a.Type().Implements(hasherType) {
+		return false
+	}
+
+	if (a.Kind() == reflect.Ptr || a.Kind() == reflect.Interface) && (a.IsNil() || b.IsNil()) {
+		return false
+	}
+
+	return exportInterface(a).(Hasher).Hash() == exportInterface(b).(Hasher).Hash()
+}
+
+// isComparable returns true if the type implements Comparable. Pointers whose element
+// type implements it are left to diffPtr, so that nil values are handled consistently
+func isComparable(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr && t.Elem().Implements(comparableType) {
+		return false
+	}
+	return t.Implements(comparableType)
+}
+
+func isTime(v reflect.Value) bool {
+	if v.Kind() == reflect.Invalid {
+		return false
+	}
+
+	t := v.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t == reflect.TypeOf(time.Time{})
+}
+
 func copyAppend(src []string, elems ...string) []string {
 	dst := make([]string, len(src)+len(elems))
 	copy(dst, src)
@@ -415,3 +797,18 @@ func copyAppend(src []string, elems ...string) []string {
 	}
 	return dst
 }
+
+// atomic returns true if the value's type has been marked as atomic
+func (d *Differ) atomic(v reflect.Value) bool {
+	if !v.IsValid() {
+		return false
+	}
+
+	for _, t := range d.AtomicTypes {
+		if v.Type() == t {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,78 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"math/big"
+	"reflect"
+)
+
+var bigTypes = []reflect.Type{
+	reflect.TypeOf(&big.Int{}),
+	reflect.TypeOf(&big.Float{}),
+	reflect.TypeOf(&big.Rat{}),
+}
+
+// diffBig compares arbitrary precision numbers by their value. A nil number is reported as created
+// or deleted
+func (d *Differ) diffBig(path []string, a, b reflect.Value, parent interface{}) error {
+	anil := !a.IsValid() || a.IsNil()
+	bnil := !b.IsValid() || b.IsNil()
+
+	switch {
+	case anil && bnil:
+		return nil
+	case anil:
+		d.cl.Add(CREATE, path, nil, exportInterface(b), parent)
+		return nil
+	case bnil:
+		d.cl.Add(DELETE, path, exportInterface(a), nil, parent)
+		return nil
+	}
+
+	if bigCmp(exportInterface(a), exportInterface(b)) != 0 {
+		d.cl.Add(UPDATE, path, exportInterface(a), exportInterface(b), parent)
+	} else {
+		d.unchanged(path, a, b, parent)
+	}
+
+	return nil
+}
+
+func bigCmp(a, b interface{}) int {
+	switch av := a.(type) {
+	case *big.Int:
+		return av.Cmp(b.(*big.Int))
+	case *big.Float:
+		return av.Cmp(b.(*big.Float))
+	case *big.Rat:
+		return av.Cmp(b.(*big.Rat))
+	}
+	return 0
+}
+
+// isBig returns true if the values are arbitrary precision numbers of the same type
+func isBig(a, b reflect.Value) bool {
+	if a.IsValid() && b.IsValid() && a.Type() != b.Type() {
+		return false
+	}
+
+	v := a
+	if !v.IsValid() {
+		v = b
+	}
+
+	if !v.IsValid() {
+		return false
+	}
+
+	for _, t := range bigTypes {
+		if v.Type() == t {
+			return true
+		}
+	}
+
+	return false
+}
@@ -23,6 +23,8 @@ func (d *Differ) diffBool(path []string, a, b reflect.Value, parent interface{})
 
 	if a.Bool() != b.Bool() {
 		d.cl.Add(UPDATE, path, exportInterface(a), exportInterface(b), parent)
+	} else {
+		d.unchanged(path, a, b, parent)
 	}
 
 	return nil
@@ -0,0 +1,71 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+)
+
+func (d *Differ) diffBytes(path []string, a, b reflect.Value, parent interface{}) error {
+	if a.Kind() == reflect.Invalid {
+		d.cl.Add(CREATE, path, nil, d.bytesValue(b))
+		return nil
+	}
+
+	if b.Kind() == reflect.Invalid {
+		d.cl.Add(DELETE, path, d.bytesValue(a), nil)
+		return nil
+	}
+
+	if a.Kind() != b.Kind() {
+		return ErrTypeMismatch
+	}
+
+	if !bytes.Equal(bytesOf(a), bytesOf(b)) {
+		d.cl.Add(UPDATE, path, d.bytesValue(a), d.bytesValue(b), parent)
+	} else {
+		d.unchanged(path, a, b, parent)
+	}
+
+	return nil
+}
+
+// bytesValue returns the value to store in the changelog for a byte slice
+func (d *Differ) bytesValue(v reflect.Value) interface{} {
+	if d.HexEncodeBytes {
+		return hex.EncodeToString(bytesOf(v))
+	}
+	return exportInterface(v)
+}
+
+// bytesOf returns the bytes held by a byte slice or array
+func bytesOf(v reflect.Value) []byte {
+	if v.Kind() == reflect.Array {
+		b := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(b), v)
+		return b
+	}
+	return v.Bytes()
+}
+
+func isBytes(a, b reflect.Value) bool {
+	for _, v := range []reflect.Value{a, b} {
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return true
+		}
+	}
+	return false
+}
+
+func isByteArray(a, b reflect.Value) bool {
+	for _, v := range []reflect.Value{a, b} {
+		if v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8 {
+			return true
+		}
+	}
+	return false
+}
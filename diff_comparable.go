@@ -0,0 +1,75 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import "reflect"
+
+func (d *Differ) diffComparable(path []string, a, b reflect.Value, parent interface{}) error {
+	if a.Kind() == reflect.Ptr || a.Kind() == reflect.Interface {
+		if a.IsNil() && b.IsNil() {
+			d.unchanged(path, a, b, parent)
+			return nil
+		}
+
+		if a.IsNil() {
+			d.cl.Add(UPDATE, path, nil, exportInterface(b), parent)
+			return nil
+		}
+
+		if b.IsNil() {
+			d.cl.Add(UPDATE, path, exportInterface(a), nil, parent)
+			return nil
+		}
+	}
+
+	ai := exportInterface(a).(Comparable)
+	if !ai.DiffEqual(exportInterface(b)) {
+		d.cl.Add(UPDATE, path, exportInterface(a), exportInterface(b), parent)
+	} else {
+		d.unchanged(path, a, b, parent)
+	}
+
+	return nil
+}
+
+// diffEqualityMethod compares values by calling their equality method, as set with EqualityMethod
+func (d *Differ) diffEqualityMethod(path []string, a, b reflect.Value, parent interface{}) error {
+	if a.Kind() == reflect.Ptr || a.Kind() == reflect.Interface {
+		if a.IsNil() && b.IsNil() {
+			d.unchanged(path, a, b, parent)
+			return nil
+		}
+
+		if a.IsNil() || b.IsNil() {
+			d.cl.Add(UPDATE, path, exportInterface(a), exportInterface(b), parent)
+			return nil
+		}
+	}
+
+	av, bv := exportInterface(a), exportInterface(b)
+
+	equal := reflect.ValueOf(av).MethodByName(d.EqualityMethod).Call([]reflect.Value{reflect.ValueOf(bv)})
+	if !equal[0].Bool() {
+		d.cl.Add(UPDATE, path, av, bv, parent)
+	} else {
+		d.unchanged(path, a, b, parent)
+	}
+
+	return nil
+}
+
+// hasEqualityMethod returns true if the type has a method with the name, which takes
+// a value of the same type and returns a bool
+func hasEqualityMethod(t reflect.Type, name string) bool {
+	m, ok := t.MethodByName(name)
+	if !ok {
+		return false
+	}
+
+	mt := m.Type
+
+	return mt.NumIn() == 2 && mt.NumOut() == 1 &&
+		t.AssignableTo(mt.In(1)) && mt.Out(0).Kind() == reflect.Bool
+}
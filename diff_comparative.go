@@ -6,16 +6,31 @@ package diff
 
 import (
 	"reflect"
+	"sort"
 )
 
 func (d *Differ) diffComparative(path []string, c *ComparativeList, parent interface{}) error {
+	ids := make(map[interface{}]string, len(c.keys))
 	for _, k := range c.keys {
-		id := idstring(k)
+		ids[k] = idstring(k)
 		if d.StructMapKeys {
-			id = idComplex(k)
+			ids[k] = idComplex(k)
 		}
+	}
+
+	// emit changes in a stable order, regardless of map iteration order. Slice indexes are
+	// kept in numeric order
+	sort.SliceStable(c.keys, func(i, j int) bool {
+		x, xok := c.keys[i].(int)
+		y, yok := c.keys[j].(int)
+		if xok && yok {
+			return x < y
+		}
+		return ids[c.keys[i]] < ids[c.keys[j]]
+	})
 
-		fpath := copyAppend(path, id)
+	for _, k := range c.keys {
+		fpath := copyAppend(path, ids[k])
 		nv := reflect.ValueOf(nil)
 
 		if c.m[k].A == nil {
@@ -26,37 +41,86 @@ func (d *Differ) diffComparative(path []string, c *ComparativeList, parent inter
 			c.m[k].B = &nv
 		}
 
+		// values present on both sides may be compared as a whole by a custom predicate
+		if c.equal != nil && c.m[k].A.IsValid() && c.m[k].B.IsValid() {
+			av, bv := exportInterface(*c.m[k].A), exportInterface(*c.m[k].B)
+			if !c.equal(av, bv) {
+				d.cl.Add(UPDATE, fpath, av, bv, parent)
+			} else if d.IncludeUnchanged {
+				d.cl.Add(EQUAL, fpath, av, bv, parent)
+			}
+			continue
+		}
+
+		start := len(d.cl)
+
+		if d.TrackComparativeIndices {
+			d.held++
+		}
+
 		err := d.diff(fpath, *c.m[k].A, *c.m[k].B, parent)
 		if err != nil {
 			return err
 		}
+
+		if d.TrackComparativeIndices {
+			d.cl.setIndices(start, len(fpath), c.m[k].ai, c.m[k].bi)
+			d.held--
+		}
 	}
 
 	return nil
 }
 
-func (d *Differ) comparative(a, b reflect.Value) bool {
-	if a.Len() > 0 {
-		ae := a.Index(0)
-		ak := getFinalValue(ae)
+// setIndices records element positions, along with the length of the path to the element, on
+// changes from start onwards. Changes that already belong to a nested identified element keep
+// their own positions
+func (cl Changelog) setIndices(start int, element int, from, to *int) {
+	// maps and unidentified slices have no positions to record
+	if from == nil && to == nil {
+		return
+	}
 
-		if ak.Kind() == reflect.Struct {
-			if identifier(d.TagName, ak) != nil {
-				return true
-			}
+	for i := start; i < len(cl); i++ {
+		if cl[i].FromIndex != nil || cl[i].ToIndex != nil {
+			continue
 		}
+		cl[i].FromIndex, cl[i].ToIndex = from, to
+		cl[i].element = element
 	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
 
-	if b.Len() > 0 {
-		be := b.Index(0)
-		bk := getFinalValue(be)
+// comparative returns true if the elements of the slices carry an identifier. Pointer and
+// interface elements are resolved to the value they hold, skipping any that are nil. Slices
+// that mix elements with and without an identifier aren't comparative, and are reported as mixed
+func (d *Differ) comparative(a, b reflect.Value) (ok bool, mixed bool) {
+	var with, without int
+
+	for _, s := range []reflect.Value{a, b} {
+		// elements of the same type all have an identifier, or none do
+		homogeneous := s.Type().Elem().Kind() != reflect.Interface
+
+		for i := 0; i < s.Len(); i++ {
+			v := getFinalValue(s.Index(i))
+			if !v.IsValid() {
+				continue
+			}
+
+			if v.Kind() == reflect.Struct && identifier(d.TagName, v) != nil {
+				with++
+			} else {
+				without++
+			}
 
-		if bk.Kind() == reflect.Struct {
-			if identifier(d.TagName, bk) != nil {
-				return true
+			if homogeneous {
+				break
 			}
 		}
 	}
 
-	return false
+	return with > 0 && without == 0, with > 0 && without > 0
 }
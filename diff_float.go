@@ -24,11 +24,13 @@ func (d *Differ) diffFloat(path []string, a, b reflect.Value, parent interface{}
 	}
 
 	if a.Float() != b.Float() {
-		if a.CanInterface() {
+		if a.CanInterface() || d.IncludeUnexported {
 			d.cl.Add(UPDATE, path, exportInterface(a), exportInterface(b), parent)
 		} else {
 			d.cl.Add(UPDATE, path, a.Float(), b.Float(), parent)
 		}
+	} else {
+		d.unchanged(path, a, b, parent)
 	}
 
 	return nil
@@ -24,11 +24,15 @@ func (d *Differ) diffInt(path []string, a, b reflect.Value, parent interface{})
 	}
 
 	if a.Int() != b.Int() {
-		if a.CanInterface() {
+		// named types such as time.Duration are exported so that their concrete
+		// type is preserved in the changelog rather than reported as int64
+		if a.CanInterface() || a.Type().PkgPath() != "" || d.IncludeUnexported {
 			d.cl.Add(UPDATE, path, exportInterface(a), exportInterface(b), parent)
 		} else {
 			d.cl.Add(UPDATE, path, a.Int(), b.Int(), parent)
 		}
+	} else {
+		d.unchanged(path, a, b, parent)
 	}
 
 	return nil
@@ -22,6 +22,7 @@ func (d *Differ) diffInterface(path []string, a, b reflect.Value, parent interfa
 	}
 
 	if a.IsNil() && b.IsNil() {
+		d.unchanged(path, a, b, parent)
 		return nil
 	}
 
@@ -35,5 +36,31 @@ func (d *Differ) diffInterface(path []string, a, b reflect.Value, parent interfa
 		return nil
 	}
 
+	// interfaces may hold values of different types, which are replaced as a whole unless one of
+	// the options below considers them equal. This applies whether or not type mismatches are
+	// allowed, as both values are valid for the interface
+	if a.Elem().Type() != b.Elem().Type() {
+		if d.DereferencePointers {
+			if _, _, ok := dereference(a.Elem(), b.Elem()); ok {
+				return d.diff(path, a.Elem(), b.Elem(), parent)
+			}
+		}
+		// a struct and a pointer to it are interchangeable when nil equals the zero struct
+		if d.NilEqualsZeroStruct && baseType(a.Elem().Type()) == baseType(b.Elem().Type()) {
+			if zeroStruct(a.Elem()) && zeroStruct(b.Elem()) {
+				d.unchanged(path, a, b, parent)
+			} else {
+				d.cl.Add(UPDATE, path, exportInterface(a), exportInterface(b), parent)
+			}
+			return nil
+		}
+		if d.NumericEquality && numericEqual(a.Elem(), b.Elem()) {
+			d.unchanged(path, a, b, parent)
+			return nil
+		}
+		d.cl.Add(UPDATE, path, exportInterface(a), exportInterface(b), parent)
+		return nil
+	}
+
 	return d.diff(path, a.Elem(), b.Elem(), parent)
 }
@@ -0,0 +1,106 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"net"
+	"reflect"
+)
+
+var (
+	ipType    = reflect.TypeOf(net.IP{})
+	ipNetType = reflect.TypeOf(&net.IPNet{})
+)
+
+// diffIP compares ip addresses and networks by value rather than byte by byte, so the 4 and 16
+// byte forms of an IPv4 address are equal. Changes hold the textual form of the values. A nil or
+// empty value is reported as created or deleted
+func (d *Differ) diffIP(path []string, a, b reflect.Value, parent interface{}) error {
+	as, aok := ipString(a)
+	bs, bok := ipString(b)
+
+	switch {
+	case !aok && !bok:
+		return nil
+	case !aok:
+		d.cl.Add(CREATE, path, nil, bs, parent)
+		return nil
+	case !bok:
+		d.cl.Add(DELETE, path, as, nil, parent)
+		return nil
+	}
+
+	if !ipEqual(exportInterface(a), exportInterface(b)) {
+		d.cl.Add(UPDATE, path, as, bs, parent)
+	} else {
+		d.unchanged(path, a, b, parent)
+	}
+
+	return nil
+}
+
+// ipString returns the textual form of an ip address or network, and false if it is nil or empty
+func ipString(v reflect.Value) (string, bool) {
+	if !v.IsValid() || v.IsNil() {
+		return "", false
+	}
+
+	switch x := exportInterface(v).(type) {
+	case net.IP:
+		if len(x) == 0 {
+			return "", false
+		}
+		return x.String(), true
+	case *net.IPNet:
+		return x.String(), true
+	}
+
+	return "", false
+}
+
+func ipEqual(a, b interface{}) bool {
+	switch av := a.(type) {
+	case net.IP:
+		return av.Equal(b.(net.IP))
+	case *net.IPNet:
+		return av.String() == b.(*net.IPNet).String()
+	}
+	return false
+}
+
+// isIP returns true if the values are ip addresses or networks of the same type
+func isIP(a, b reflect.Value) bool {
+	if a.IsValid() && b.IsValid() && a.Type() != b.Type() {
+		return false
+	}
+
+	v := a
+	if !v.IsValid() {
+		v = b
+	}
+
+	return v.IsValid() && (v.Type() == ipType || v.Type() == ipNetType)
+}
+
+// parseIP converts the textual form of an ip address or network back to a value of type t.
+// Returns false if t isn't an ip type
+func parseIP(s string, t reflect.Type) (reflect.Value, bool, error) {
+	switch t {
+	case ipType:
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return reflect.Value{}, true, NewErrorf("invalid ip address %s", s)
+		}
+		return reflect.ValueOf(ip), true, nil
+	case ipNetType:
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return reflect.Value{}, true, err
+		}
+		return reflect.ValueOf(n), true, nil
+	}
+
+	return reflect.Value{}, false, nil
+}
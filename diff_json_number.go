@@ -0,0 +1,52 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"regexp"
+)
+
+var (
+	jsonNumberType = reflect.TypeOf(json.Number(""))
+
+	// jsonNumberSyntax matches numbers as written in json, which big.Rat accepts along with others
+	// such as fractions
+	jsonNumberSyntax = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+)
+
+// diffJSONNumber compares json numbers by numeric value, so that "1" and "1.0" are equal. Values
+// that aren't valid json numbers are compared as text
+func (d *Differ) diffJSONNumber(path []string, a, b reflect.Value, parent interface{}) error {
+	ar, aok := jsonNumberValue(a.String())
+	br, bok := jsonNumberValue(b.String())
+
+	if aok && bok {
+		if ar.Cmp(br) != 0 {
+			d.cl.Add(UPDATE, path, exportInterface(a), exportInterface(b), parent)
+		} else {
+			d.unchanged(path, a, b, parent)
+		}
+		return nil
+	}
+
+	if a.String() != b.String() {
+		d.cl.Add(UPDATE, path, exportInterface(a), exportInterface(b), parent)
+	} else {
+		d.unchanged(path, a, b, parent)
+	}
+
+	return nil
+}
+
+// jsonNumberValue returns the numeric value of a json number, and false if it isn't valid
+func jsonNumberValue(s string) (*big.Rat, bool) {
+	if !jsonNumberSyntax.MatchString(s) {
+		return nil, false
+	}
+	return new(big.Rat).SetString(s)
+}
@@ -0,0 +1,102 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"reflect"
+	"sort"
+)
+
+// LazyGetter returns the value found at path, and whether there is one
+type LazyGetter func(path []string) (interface{}, bool)
+
+// DiffLazy compares a with a value that is fetched on demand with bGetter, rather than being held in
+// memory as a whole. The structs and maps of a are descended into, and the value of each of their
+// fields and keys is fetched from bGetter by its path. All other values are fetched and compared as
+// a whole. Map keys that are only present on the fetched side can't be known, so aren't reported
+func (d *Differ) DiffLazy(a interface{}, bGetter LazyGetter) (Changelog, error) {
+	// reset the state of the diff
+	d.cl = Changelog{}
+
+	err := d.diffLazy([]string{}, reflect.ValueOf(a), bGetter)
+
+	d.finish(d.cl)
+
+	return d.cl, err
+}
+
+func (d *Differ) diffLazy(path []string, a reflect.Value, get LazyGetter) error {
+	for a.Kind() == reflect.Ptr || a.Kind() == reflect.Interface {
+		if a.IsNil() {
+			break
+		}
+		a = a.Elem()
+	}
+
+	switch {
+	case a.Kind() == reflect.Struct && !isTime(a):
+		return d.diffLazyStruct(path, a, get)
+	case a.Kind() == reflect.Map && !a.IsNil():
+		return d.diffLazyMap(path, a, get)
+	}
+
+	var b reflect.Value
+	if bv, ok := get(path); ok {
+		b = reflect.ValueOf(bv)
+	}
+
+	return d.diff(path, a, b, nil)
+}
+
+func (d *Differ) diffLazyStruct(path []string, a reflect.Value, get LazyGetter) error {
+	for i := 0; i < a.NumField(); i++ {
+		field := a.Type().Field(i)
+		tname := tagName(d.TagName, field, d.TagFallback...)
+
+		if tname == "-" || field.PkgPath != "" {
+			continue
+		}
+
+		if tname == "" {
+			tname = field.Name
+		}
+
+		fpath := copyAppend(path, tname)
+
+		if d.Filter != nil && !d.Filter(fpath, a.Type(), field) {
+			continue
+		}
+
+		err := d.diffLazy(fpath, a.Field(i), get)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Differ) diffLazyMap(path []string, a reflect.Value, get LazyGetter) error {
+	keys := a.MapKeys()
+	ids := make([]string, len(keys))
+
+	for i, k := range keys {
+		ids[i] = idstring(exportInterface(k))
+		if d.StructMapKeys {
+			ids[i] = idComplex(exportInterface(k))
+		}
+	}
+
+	sort.Sort(keySorter{keys, ids})
+
+	for i, k := range keys {
+		err := d.diffLazy(copyAppend(path, ids[i]), a.MapIndex(k), get)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -7,11 +7,23 @@ package diff
 import (
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/vmihailenco/msgpack/v5"
 )
 
 func (d *Differ) diffMap(path []string, a, b reflect.Value, parent interface{}) error {
+	if d.MapSeparator != "" && isFlatMap(a) && isFlatMap(b) {
+		var err error
+		if a, err = d.flattenMap(a); err != nil {
+			return err
+		}
+		if b, err = d.flattenMap(b); err != nil {
+			return err
+		}
+	}
+
 	if a.Kind() == reflect.Invalid {
 		return d.mapValues(CREATE, path, b)
 	}
@@ -20,7 +32,12 @@ func (d *Differ) diffMap(path []string, a, b reflect.Value, parent interface{})
 		return d.mapValues(DELETE, path, a)
 	}
 
+	if d.MapValueKey != nil {
+		return d.diffMapByValueKey(path, a, b)
+	}
+
 	c := NewComparativeList()
+	c.equal = d.MapValueEqual
 
 	for _, k := range a.MapKeys() {
 		ae := a.MapIndex(k)
@@ -32,9 +49,168 @@ func (d *Differ) diffMap(path []string, a, b reflect.Value, parent interface{})
 		c.addB(exportInterface(k), &be)
 	}
 
+	if d.MapDefault != nil {
+		d.mapDefaults(c, a.Type().Elem())
+	}
+
+	if d.OmitEmpty {
+		omitEmptyKeys(c)
+	}
+
 	return d.diffComparative(path, c, exportInterface(a))
 }
 
+type keyedEntry struct {
+	k, v reflect.Value
+}
+
+// diffMapByValueKey pairs up the entries of both maps by the key MapValueKey derives from their
+// values. Changes are reported at the entries' map keys. An entry that moved to a different map key
+// is reported as the deletion of the old key and the creation of the new key with its previous value,
+// followed by the changes to its value under the new key. All keys that are vacated are deleted before
+// any are created, so entries that swapped keys can be patched
+func (d *Differ) diffMapByValueKey(path []string, a, b reflect.Value) error {
+	ae, err := d.valueKeyedEntries(a)
+	if err != nil {
+		return err
+	}
+
+	be, err := d.valueKeyedEntries(b)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]interface{}, 0, len(ae)+len(be))
+	for k := range ae {
+		keys = append(keys, k)
+	}
+	for k := range be {
+		if _, ok := ae[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		return idstring(keys[i]) < idstring(keys[j])
+	})
+
+	parent := exportInterface(a)
+
+	// entries that were removed or moved away from their key
+	for _, k := range keys {
+		x, xok := ae[k]
+		y, yok := be[k]
+
+		switch {
+		case xok && !yok:
+			err := d.diff(copyAppend(path, d.mapKeyID(x.k)), x.v, reflect.Value{}, parent)
+			if err != nil {
+				return err
+			}
+		case xok && d.mapKeyID(x.k) != d.mapKeyID(y.k):
+			d.cl.Add(DELETE, copyAppend(path, d.mapKeyID(x.k)), exportInterface(x.v), nil)
+		}
+	}
+
+	// entries that were moved to their new key
+	for _, k := range keys {
+		x, xok := ae[k]
+		y, yok := be[k]
+
+		if !xok || !yok || d.mapKeyID(x.k) == d.mapKeyID(y.k) {
+			continue
+		}
+
+		to := copyAppend(path, d.mapKeyID(y.k))
+		d.cl.Add(CREATE, to, nil, exportInterface(x.v))
+
+		err := d.diff(to, x.v, y.v, parent)
+		if err != nil {
+			return err
+		}
+	}
+
+	// entries that kept their key, or were added
+	for _, k := range keys {
+		x, xok := ae[k]
+		y, yok := be[k]
+
+		var err error
+		switch {
+		case !xok:
+			err = d.diff(copyAppend(path, d.mapKeyID(y.k)), reflect.Value{}, y.v, parent)
+		case yok && d.mapKeyID(x.k) == d.mapKeyID(y.k):
+			err = d.diff(copyAppend(path, d.mapKeyID(y.k)), x.v, y.v, parent)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// valueKeyedEntries indexes the entries of the map by the key derived from their values. Derived
+// keys must be unique within each map
+func (d *Differ) valueKeyedEntries(m reflect.Value) (map[interface{}]keyedEntry, error) {
+	entries := make(map[interface{}]keyedEntry, m.Len())
+
+	for _, k := range m.MapKeys() {
+		v := m.MapIndex(k)
+		vk := d.MapValueKey(exportInterface(v))
+
+		if e, ok := entries[vk]; ok {
+			return nil, NewErrorf("map entries %v and %v share the value key %v", exportInterface(e.k), exportInterface(k), vk)
+		}
+
+		entries[vk] = keyedEntry{k, v}
+	}
+
+	return entries, nil
+}
+
+// mapKeyID returns the path segment of a map key
+func (d *Differ) mapKeyID(k reflect.Value) string {
+	if d.StructMapKeys {
+		return idComplex(exportInterface(k))
+	}
+	return idstring(exportInterface(k))
+}
+
+// mapDefaults substitutes the default value for keys that are missing from either map
+func (d *Differ) mapDefaults(c *ComparativeList, t reflect.Type) {
+	for _, k := range c.keys {
+		if c.m[k].A != nil && c.m[k].B != nil {
+			continue
+		}
+
+		def, ok := d.MapDefault(k)
+		if !ok {
+			continue
+		}
+
+		dv := reflect.ValueOf(def)
+		v := reflect.New(t).Elem()
+
+		switch {
+		case !dv.IsValid():
+		case dv.Type().AssignableTo(t):
+			v.Set(dv)
+		case dv.Type().ConvertibleTo(t):
+			v.Set(dv.Convert(t))
+		default:
+			continue
+		}
+
+		if c.m[k].A == nil {
+			c.m[k].A = &v
+		} else {
+			c.m[k].B = &v
+		}
+	}
+}
+
 func (d *Differ) mapValues(t string, path []string, a reflect.Value) error {
 	if t != CREATE && t != DELETE {
 		return ErrInvalidChangeType
@@ -50,21 +226,34 @@ func (d *Differ) mapValues(t string, path []string, a reflect.Value) error {
 
 	x := reflect.New(a.Type()).Elem()
 
-	for _, k := range a.MapKeys() {
-		ae := a.MapIndex(k)
-		xe := x.MapIndex(k)
+	keys := a.MapKeys()
+	ids := make([]string, len(keys))
 
-		var err error
+	for i, k := range keys {
 		if d.StructMapKeys {
 			//it's not enough to turn k to a string, we need to able to  marshal a type when
 			//we apply it in patch so... we'll marshal it to JSON
-			var b []byte
-			if b, err = msgpack.Marshal(k.Interface()); err == nil {
-				err = d.diff(append(path, string(b)), xe, ae, a.Interface())
+			b, err := msgpack.Marshal(k.Interface())
+			if err != nil {
+				return err
 			}
+			ids[i] = string(b)
 		} else {
-			err = d.diff(append(path, fmt.Sprint(k.Interface())), xe, ae, a.Interface())
+			ids[i] = fmt.Sprint(k.Interface())
 		}
+	}
+
+	sortKeys(keys, ids)
+
+	// the changes are swapped once all of them are known
+	d.held++
+	defer func() { d.held-- }()
+
+	for i, k := range keys {
+		ae := a.MapIndex(k)
+		xe := x.MapIndex(k)
+
+		err := d.diff(append(path, ids[i]), xe, ae, a.Interface())
 		if err != nil {
 			return err
 		}
@@ -79,3 +268,61 @@ func (d *Differ) mapValues(t string, path []string, a reflect.Value) error {
 
 	return nil
 }
+
+// sortKeys sorts map keys by their path element, so changes are emitted in a stable order
+func sortKeys(keys []reflect.Value, ids []string) {
+	sort.Sort(keySorter{keys, ids})
+}
+
+type keySorter struct {
+	keys []reflect.Value
+	ids  []string
+}
+
+func (s keySorter) Len() int           { return len(s.keys) }
+func (s keySorter) Less(i, j int) bool { return s.ids[i] < s.ids[j] }
+
+func (s keySorter) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.ids[i], s.ids[j] = s.ids[j], s.ids[i]
+}
+
+var flatMapType = reflect.TypeOf(map[string]interface{}{})
+
+// isFlatMap returns true if the value is missing, or can be flattened
+func isFlatMap(v reflect.Value) bool {
+	return !v.IsValid() || v.Type() == flatMapType
+}
+
+// flattenMap moves the entries of nested maps up into a single map, joining their keys with the
+// separator. Empty nested maps are kept as values. Fails on keys that hold the separator, as
+// they couldn't be told apart from the keys of nested maps once flattened
+func (d *Differ) flattenMap(v reflect.Value) (reflect.Value, error) {
+	if !v.IsValid() || v.IsNil() {
+		return v, nil
+	}
+
+	flat := make(map[string]interface{})
+	err := flatten(flat, "", v.Interface().(map[string]interface{}), d.MapSeparator)
+
+	return reflect.ValueOf(flat), err
+}
+
+func flatten(flat map[string]interface{}, prefix string, m map[string]interface{}, sep string) error {
+	for k, v := range m {
+		if strings.Contains(k, sep) {
+			return NewErrorf("map key %s holds the separator %s", prefix+k, sep)
+		}
+
+		if nm, ok := v.(map[string]interface{}); ok && len(nm) > 0 {
+			if err := flatten(flat, prefix+k+sep, nm, sep); err != nil {
+				return err
+			}
+			continue
+		}
+
+		flat[prefix+k] = v
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"math"
+	"reflect"
+)
+
+// numericEqual returns true if both values are numbers of any int, uint or float kind that hold
+// the same numeric value. Integers are compared exactly rather than as floats, so large values
+// that round to the same float are told apart
+func numericEqual(a, b reflect.Value) bool {
+	if isFloat(a) && isFloat(b) {
+		return a.Float() == b.Float()
+	}
+
+	aneg, amag, ok := integerValue(a)
+	if !ok {
+		return false
+	}
+
+	bneg, bmag, ok := integerValue(b)
+
+	return ok && aneg == bneg && amag == bmag
+}
+
+func isFloat(v reflect.Value) bool {
+	return v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64
+}
+
+// integerValue returns the sign and magnitude of an integer, or of a float holding a whole number
+// within the range of a uint64
+func integerValue(v reflect.Value) (bool, uint64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := v.Int()
+		if i < 0 {
+			// negated after adding one, as the magnitude of the smallest int64 doesn't fit in one
+			return true, uint64(-(i + 1)) + 1, true
+		}
+		return false, uint64(i), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return false, v.Uint(), true
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if f != math.Trunc(f) || math.Abs(f) >= 1<<64 {
+			return false, 0, false
+		}
+		return f < 0, uint64(math.Abs(f)), true
+	}
+
+	return false, 0, false
+}
+
+func numericValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+
+	return 0, false
+}
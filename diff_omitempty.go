@@ -0,0 +1,50 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"reflect"
+)
+
+// omitEmptyKeys removes keys that are missing on one side and hold an empty value on the other
+func omitEmptyKeys(c *ComparativeList) {
+	keys := c.keys[:0]
+
+	for _, k := range c.keys {
+		cm := c.m[k]
+		if (cm.A == nil && isEmpty(*cm.B)) || (cm.B == nil && isEmpty(*cm.A)) {
+			delete(c.m, k)
+			continue
+		}
+		keys = append(keys, k)
+	}
+
+	c.keys = keys
+}
+
+// isEmpty reports whether v would be omitted by encoding/json's omitempty. Interfaces
+// are looked through, as documents decoded into interface{} values hold them
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Interface:
+		return v.IsNil() || isEmpty(v.Elem())
+	case reflect.Ptr:
+		return v.IsNil()
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	}
+
+	return false
+}
@@ -0,0 +1,83 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import "reflect"
+
+// OrderedMapFunc returns the keys of an ordered map value, in order, along with their values
+type OrderedMapFunc func(v interface{}) (keys []interface{}, values []interface{})
+
+// diffOrderedMap compares the entries of two ordered maps by their keys, as with a map. If the
+// entries present in both have changed order, an update of the key order is reported on the path
+// of the ordered map itself. The changes are meant for reporting, they can't be patched
+func (d *Differ) diffOrderedMap(path []string, a, b reflect.Value, f OrderedMapFunc, parent interface{}) error {
+	if a.Kind() == reflect.Invalid || (a.Kind() == reflect.Ptr && a.IsNil()) {
+		d.cl.Add(CREATE, path, nil, exportInterface(b))
+		return nil
+	}
+
+	if b.Kind() == reflect.Invalid || (b.Kind() == reflect.Ptr && b.IsNil()) {
+		d.cl.Add(DELETE, path, exportInterface(a), nil)
+		return nil
+	}
+
+	ak, av := f(exportInterface(a))
+	bk, bv := f(exportInterface(b))
+
+	c := NewComparativeList()
+
+	for i := range ak {
+		v := reflect.ValueOf(av[i])
+		c.addA(ak[i], &v)
+	}
+
+	for i := range bk {
+		v := reflect.ValueOf(bv[i])
+		c.addB(bk[i], &v)
+	}
+
+	if ao, bo := sharedOrder(ak, c), sharedOrder(bk, c); !reflect.DeepEqual(ao, bo) {
+		d.cl.Add(UPDATE, path, ao, bo, parent)
+	}
+
+	return d.diffComparative(path, c, exportInterface(a))
+}
+
+// sharedOrder returns the keys, in order, that are present on both sides of the list
+func sharedOrder(keys []interface{}, c *ComparativeList) []interface{} {
+	order := make([]interface{}, 0, len(keys))
+
+	for _, k := range keys {
+		if c.m[k].A != nil && c.m[k].B != nil {
+			order = append(order, k)
+		}
+	}
+
+	return order
+}
+
+// orderedMap returns the function registered for the type of the values, if any
+func (d *Differ) orderedMap(a, b reflect.Value) (OrderedMapFunc, bool) {
+	if len(d.orderedMaps) == 0 {
+		return nil, false
+	}
+
+	if a.IsValid() && b.IsValid() && a.Type() != b.Type() {
+		return nil, false
+	}
+
+	v := a
+	if !v.IsValid() {
+		v = b
+	}
+
+	if !v.IsValid() {
+		return nil, false
+	}
+
+	f, ok := d.orderedMaps[v.Type()]
+
+	return f, ok
+}
@@ -35,6 +35,13 @@ func (d *Differ) diffPtr(path []string, a, b reflect.Value, parent interface{})
 	}
 
 	if a.IsNil() && b.IsNil() {
+		d.unchanged(path, a, b, parent)
+		return nil
+	}
+
+	// a nil struct may stand for an empty one
+	if d.NilEqualsZeroStruct && (a.IsNil() || b.IsNil()) && zeroStruct(a) && zeroStruct(b) {
+		d.unchanged(path, a, b, parent)
 		return nil
 	}
 
@@ -51,6 +58,113 @@ func (d *Differ) diffPtr(path []string, a, b reflect.Value, parent interface{})
 	return d.diff(path, reflect.Indirect(a), reflect.Indirect(b), parent)
 }
 
+// dereference indirects a pointer and a value of the type it points to, so that both can be
+// compared as the same type. Nil pointers are returned as invalid values
+func dereference(a, b reflect.Value) (reflect.Value, reflect.Value, bool) {
+	if !a.IsValid() || !b.IsValid() || a.Type() == b.Type() {
+		return a, b, false
+	}
+
+	if a.Kind() != reflect.Ptr && b.Kind() != reflect.Ptr {
+		return a, b, false
+	}
+
+	if baseType(a.Type()) != baseType(b.Type()) {
+		return a, b, false
+	}
+
+	return indirectAll(a), indirectAll(b), true
+}
+
+// baseType returns the type at the end of any number of pointers
+func baseType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// indirectAll follows pointers until it reaches a value that isn't a pointer
+func indirectAll(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// diffDereferenced compares dereferenced values, where a nil pointer has been replaced by an
+// invalid value
+func (d *Differ) diffDereferenced(path []string, a, b reflect.Value, parent interface{}) error {
+	switch {
+	case !a.IsValid() && !b.IsValid():
+		return nil
+	case d.NilEqualsZeroStruct && zeroStruct(a) && zeroStruct(b):
+		return nil
+	case !a.IsValid():
+		d.cl.Add(UPDATE, path, nil, exportInterface(b), parent)
+		return nil
+	case !b.IsValid():
+		d.cl.Add(UPDATE, path, exportInterface(a), nil, parent)
+		return nil
+	}
+
+	return d.diff(path, a, b, parent)
+}
+
+// zeroStruct returns true if the value is a nil pointer, or a struct or pointer to a struct holding
+// its zero value. Invalid values stand for nil pointers that have been dereferenced
+func zeroStruct(v reflect.Value) bool {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return baseType(v.Type()).Kind() == reflect.Struct
+		}
+		v = v.Elem()
+	}
+
+	return !v.IsValid() || (v.Kind() == reflect.Struct && v.IsZero())
+}
+
+// visit identifies a comparison of two reference values that is in progress
+type visit struct {
+	a, b   uintptr
+	an, bn int
+	t      reflect.Type
+}
+
+// visitOf returns the visit for a pair of pointers, maps or slices, which may be part of a cycle
+func visitOf(a, b reflect.Value) (visit, bool) {
+	if !a.IsValid() || !b.IsValid() || a.Type() != b.Type() {
+		return visit{}, false
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+	default:
+		return visit{}, false
+	}
+
+	if a.IsNil() || b.IsNil() {
+		return visit{}, false
+	}
+
+	v := visit{a: a.Pointer(), b: b.Pointer(), t: a.Type()}
+
+	// slices sharing an array may still differ in length
+	if a.Kind() == reflect.Slice {
+		v.an, v.bn = a.Len(), b.Len()
+	}
+
+	// the same values may be compared either way around, such as when matching slice elements
+	if v.a > v.b {
+		v.a, v.b, v.an, v.bn = v.b, v.a, v.bn, v.an
+	}
+
+	return v, true
+}
+
 func exportInterface(v reflect.Value) interface{} {
 	if !v.CanInterface() {
 		flagTmp := (*uintptr)(unsafe.Pointer(uintptr(unsafe.Pointer(&v)) + 2*unsafe.Sizeof(uintptr(0))))
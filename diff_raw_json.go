@@ -0,0 +1,54 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// diffRawJSON decodes two json documents and diffs the decoded values, so that differences in key
+// order and whitespace are not reported. If either document is not valid json, they are compared
+// byte for byte and a warning holding the decoding error is reported
+func (d *Differ) diffRawJSON(path []string, a, b reflect.Value, parent interface{}) error {
+	av, aerr := decodeRawJSON(a)
+	bv, berr := decodeRawJSON(b)
+
+	if aerr != nil || berr != nil {
+		err := aerr
+		if err == nil {
+			err = berr
+		}
+		d.cl.Add(WARNING, path, nil, err.Error())
+		return d.diffBytes(path, a, b, parent)
+	}
+
+	// decoded values may change type, such as a string becoming a number
+	allow := d.AllowTypeMismatch
+	d.AllowTypeMismatch = true
+	defer func() { d.AllowTypeMismatch = allow }()
+
+	return d.diff(path, reflect.ValueOf(&av).Elem(), reflect.ValueOf(&bv).Elem(), nil)
+}
+
+func decodeRawJSON(v reflect.Value) (interface{}, error) {
+	if !v.IsValid() || v.Len() == 0 {
+		return nil, nil
+	}
+
+	var dv interface{}
+	return dv, json.Unmarshal(v.Bytes(), &dv)
+}
+
+func isRawJSON(a, b reflect.Value) bool {
+	for _, v := range []reflect.Value{a, b} {
+		if v.IsValid() && v.Type() == rawMessageType {
+			return true
+		}
+	}
+	return false
+}
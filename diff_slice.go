@@ -6,9 +6,24 @@ package diff
 
 import (
 	"reflect"
+	"strconv"
 )
 
 func (d *Differ) diffSlice(path []string, a, b reflect.Value, parent interface{}) error {
+	// large slices of basic types may be compared in blocks
+	if d.ChunkSize > 0 && chunkable(a, b) {
+		return d.diffSliceChunked(path, a, b, parent)
+	}
+
+	if d.SemanticJSON && isRawJSON(a, b) {
+		return d.diffRawJSON(path, a, b, parent)
+	}
+
+	// byte slices and arrays are compared as a single value, rather than element by element
+	if isBytes(a, b) || isByteArray(a, b) {
+		return d.diffBytes(path, a, b, parent)
+	}
+
 	if a.Kind() == reflect.Invalid {
 		d.cl.Add(CREATE, path, nil, exportInterface(b))
 		return nil
@@ -23,14 +38,49 @@ func (d *Differ) diffSlice(path []string, a, b reflect.Value, parent interface{}
 		return ErrTypeMismatch
 	}
 
-	if d.comparative(a, b) {
+	comparative, mixed := d.comparative(a, b)
+	if comparative {
 		return d.diffSliceComparative(path, a, b)
 	}
 
+	if mixed {
+		return d.diffSliceMixed(path, a, b)
+	}
+
 	return d.diffSliceGeneric(path, a, b)
 }
 
+// diffSliceMixed compares a slice that mixes elements with and without identifiers by value. When it
+// has changed, the changes are preceded by a warning that identifiers were not used
+func (d *Differ) diffSliceMixed(path []string, a, b reflect.Value) error {
+	start := len(d.cl)
+
+	// the warning is only known to be needed once the elements have been compared
+	d.held++
+	defer func() { d.held-- }()
+
+	err := d.diffSliceGeneric(path, a, b)
+	if err != nil {
+		return err
+	}
+
+	if d.cl.changedSince(start) {
+		w := Change{Type: WARNING, Path: path, To: "slice mixes elements with and without identifiers, compared by value"}
+		d.cl = append(d.cl[:start], append(Changelog{w}, d.cl[start:]...)...)
+	}
+
+	return nil
+}
+
 func (d *Differ) diffSliceGeneric(path []string, a, b reflect.Value) error {
+	if d.SliceOrdering && d.SliceLCS && a.Kind() == reflect.Slice && b.Kind() == reflect.Slice {
+		return d.diffSliceLCS(path, a, b)
+	}
+
+	if d.PositionalStructSlices && a.Len() == b.Len() && structElems(a, b) {
+		return d.diffSlicePositional(path, a, b)
+	}
+
 	missing := NewComparativeList()
 
 	slice := sliceTracker{}
@@ -39,6 +89,13 @@ func (d *Differ) diffSliceGeneric(path []string, a, b reflect.Value) error {
 
 		if (d.SliceOrdering && !hasAtSameIndex(b, ae, i)) || (!d.SliceOrdering && !slice.has(b, ae, d)) {
 			missing.addA(i, &ae)
+		} else if d.IncludeUnchanged {
+			// the element has a match, so comparing it against itself
+			// records each of its values as unchanged
+			err := d.diff(copyAppend(path, strconv.Itoa(i)), ae, ae, exportInterface(a))
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -56,9 +113,66 @@ func (d *Differ) diffSliceGeneric(path []string, a, b reflect.Value) error {
 		return nil
 	}
 
+	if d.SliceOrdering && d.ReorderAsWhole && d.reordered(a, b) {
+		d.cl.Add(UPDATE, path, exportInterface(a), exportInterface(b))
+		return nil
+	}
+
+	// near equal numbers are paired up before the remaining elements are compared by index
+	if !d.SliceOrdering && d.FuzzySliceThreshold > 0 {
+		var err error
+		missing, err = d.diffSliceFuzzy(path, missing, exportInterface(a))
+		if err != nil {
+			return err
+		}
+	}
+
 	return d.diffComparative(path, missing, exportInterface(a))
 }
 
+// diffSlicePositional compares the elements of both slices at the same positions, so
+// that changes to an element are reported on its fields
+func (d *Differ) diffSlicePositional(path []string, a, b reflect.Value) error {
+	for i := 0; i < a.Len(); i++ {
+		err := d.diff(copyAppend(path, strconv.Itoa(i)), a.Index(i), b.Index(i), exportInterface(a))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// structElems returns true if both slices are of the same type, holding structs or pointers to them
+func structElems(a, b reflect.Value) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	t := a.Type().Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct
+}
+
+// reordered returns true if both slices hold the same elements, regardless of their order
+func (d *Differ) reordered(a, b reflect.Value) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+
+	slice := sliceTracker{}
+	for i := 0; i < b.Len(); i++ {
+		if !slice.has(a, b.Index(i), d) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (d *Differ) diffSliceComparative(path []string, a, b reflect.Value) error {
 	c := NewComparativeList()
 
@@ -69,6 +183,7 @@ func (d *Differ) diffSliceComparative(path []string, a, b reflect.Value) error {
 		id := identifier(d.TagName, ak)
 		if id != nil {
 			c.addA(id, &ae)
+			c.m[id].ai = intPtr(i)
 		}
 	}
 
@@ -79,12 +194,42 @@ func (d *Differ) diffSliceComparative(path []string, a, b reflect.Value) error {
 		id := identifier(d.TagName, bk)
 		if id != nil {
 			c.addB(id, &be)
+			c.m[id].bi = intPtr(i)
 		}
 	}
 
+	if d.WarnOnIdentifierChange {
+		d.identifierChanges(path, a, b, c)
+	}
+
 	return d.diffComparative(path, c, exportInterface(a))
 }
 
+// identifierChanges warns about elements at the same position of both slices, whose identifiers
+// are only present in one of the slices. These are likely to be the same element with a changed
+// identifier, which will be reported as a delete and a create
+func (d *Differ) identifierChanges(path []string, a, b reflect.Value, c *ComparativeList) {
+	for i := 0; i < a.Len() && i < b.Len(); i++ {
+		aid := identifier(d.TagName, getFinalValue(a.Index(i)))
+		bid := identifier(d.TagName, getFinalValue(b.Index(i)))
+
+		if aid == nil || bid == nil || aid == bid {
+			continue
+		}
+
+		if c.m[aid].B != nil || c.m[bid].A != nil {
+			continue
+		}
+
+		id := idstring(aid)
+		if d.StructMapKeys {
+			id = idComplex(aid)
+		}
+
+		d.cl.Add(WARNING, copyAppend(path, id), aid, bid)
+	}
+}
+
 // keeps track of elements that have already been matched, to stop duplicate matches from occurring
 type sliceTracker []bool
 
@@ -104,6 +249,8 @@ func (st *sliceTracker) has(s, v reflect.Value, d *Differ) bool {
 		var nd Differ
 		nd.Filter = d.Filter
 		nd.customValueDiffers = d.customValueDiffers
+		nd.visiting = d.visiting
+		nd.IncludeUnexported = d.IncludeUnexported
 
 		err := nd.diff([]string{}, x, v, nil)
 		if err != nil {
@@ -0,0 +1,142 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+)
+
+// diffSliceChunked compares two large slices block by block, reporting each run of differing
+// elements as a single change. The change is located at the offset of the run, and holds
+// sub-slices of the differing elements. Elements beyond the length of the shorter slice are
+// reported as a single create or delete
+func (d *Differ) diffSliceChunked(path []string, a, b reflect.Value, parent interface{}) error {
+	a = reflect.ValueOf(exportInterface(a))
+	b = reflect.ValueOf(exportInterface(b))
+
+	n := a.Len()
+	if b.Len() < n {
+		n = b.Len()
+	}
+
+	changed := false
+	start := -1
+
+	emit := func(end int) {
+		if start < 0 {
+			return
+		}
+		d.cl.Add(UPDATE, copyAppend(path, strconv.Itoa(start)), d.chunk(a, start, end), d.chunk(b, start, end), parent)
+		changed = true
+		start = -1
+	}
+
+	for off := 0; off < n; {
+		end := off + d.ChunkSize
+		if end > n {
+			end = n
+		}
+
+		// only look at individual elements of blocks that differ
+		if blockEqual(a, b, off, end) {
+			emit(off)
+			off = end
+			continue
+		}
+
+		for i := off; i < end; i++ {
+			if !elemEqual(a, b, i) {
+				if start < 0 {
+					start = i
+				}
+			} else {
+				emit(i)
+			}
+		}
+
+		off = end
+	}
+
+	emit(n)
+
+	if b.Len() > n {
+		d.cl.Add(CREATE, copyAppend(path, strconv.Itoa(n)), nil, d.chunk(b, n, b.Len()))
+		changed = true
+	}
+
+	if a.Len() > n {
+		d.cl.Add(DELETE, copyAppend(path, strconv.Itoa(n)), d.chunk(a, n, a.Len()), nil)
+		changed = true
+	}
+
+	if !changed {
+		d.unchanged(path, a, b, parent)
+	}
+
+	return nil
+}
+
+// chunk returns a copy of the elements of v between i and j
+func (d *Differ) chunk(v reflect.Value, i, j int) interface{} {
+	s := reflect.MakeSlice(v.Type(), j-i, j-i)
+	reflect.Copy(s, v.Slice(i, j))
+
+	if s.Type().Elem().Kind() == reflect.Uint8 {
+		return d.bytesValue(s)
+	}
+
+	return s.Interface()
+}
+
+func blockEqual(a, b reflect.Value, i, j int) bool {
+	if a.Type().Elem().Kind() == reflect.Uint8 {
+		return bytes.Equal(a.Bytes()[i:j], b.Bytes()[i:j])
+	}
+
+	for x := i; x < j; x++ {
+		if !elemEqual(a, b, x) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func elemEqual(a, b reflect.Value, i int) bool {
+	ae, be := a.Index(i), b.Index(i)
+
+	switch ae.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return ae.Int() == be.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return ae.Uint() == be.Uint()
+	case reflect.Float32, reflect.Float64:
+		return ae.Float() == be.Float()
+	case reflect.Bool:
+		return ae.Bool() == be.Bool()
+	case reflect.String:
+		return ae.String() == be.String()
+	}
+
+	return false
+}
+
+// chunkable returns true if both values are slices of the same basic type
+func chunkable(a, b reflect.Value) bool {
+	if a.Kind() != reflect.Slice || b.Kind() != reflect.Slice || a.Type() != b.Type() {
+		return false
+	}
+
+	switch a.Type().Elem().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Bool, reflect.String:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,83 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+type fuzzyElem struct {
+	i       int
+	v       reflect.Value
+	n       float64
+	numeric bool
+}
+
+func newFuzzyElem(i int, v reflect.Value) fuzzyElem {
+	n, ok := numericValue(getFinalValue(v))
+	return fuzzyElem{i, v, n, ok}
+}
+
+// diffSliceFuzzy pairs each numeric element missing from b with the nearest element missing from a
+// that is within the threshold, reporting the pair as an update at the element's index in a. Elements
+// are paired greedily in order of their index. The elements that are left unpaired are returned
+func (d *Differ) diffSliceFuzzy(path []string, missing *ComparativeList, parent interface{}) (*ComparativeList, error) {
+	var as, bs []fuzzyElem
+
+	for _, k := range missing.keys {
+		i := k.(int)
+		c := missing.m[k]
+
+		if c.A != nil {
+			as = append(as, newFuzzyElem(i, *c.A))
+		}
+
+		if c.B != nil {
+			bs = append(bs, newFuzzyElem(i, *c.B))
+		}
+	}
+
+	sort.Slice(as, func(i, j int) bool { return as[i].i < as[j].i })
+	sort.Slice(bs, func(i, j int) bool { return bs[i].i < bs[j].i })
+
+	unpaired := NewComparativeList()
+	paired := make([]bool, len(bs))
+
+	for i := range as {
+		ae := &as[i]
+		best := -1
+		for j, be := range bs {
+			if !ae.numeric || !be.numeric || paired[j] || math.Abs(ae.n-be.n) > d.FuzzySliceThreshold {
+				continue
+			}
+			if best < 0 || math.Abs(ae.n-be.n) < math.Abs(ae.n-bs[best].n) {
+				best = j
+			}
+		}
+
+		if best < 0 {
+			unpaired.addA(ae.i, &ae.v)
+			continue
+		}
+
+		paired[best] = true
+
+		err := d.diff(copyAppend(path, strconv.Itoa(ae.i)), ae.v, bs[best].v, parent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for j := range bs {
+		if !paired[j] {
+			unpaired.addB(bs[j].i, &bs[j].v)
+		}
+	}
+
+	return unpaired, nil
+}
@@ -0,0 +1,182 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"reflect"
+	"strconv"
+)
+
+type lcsOpType uint8
+
+const (
+	lcsEqual lcsOpType = iota
+	lcsDelete
+	lcsInsert
+)
+
+// lcsOp is a single step of an edit script, referencing indexes of the slices being compared
+type lcsOp struct {
+	t    lcsOpType
+	a, b int
+}
+
+// diffSliceLCS reports the minimal set of insertions and deletions that turn a into b, based on their
+// longest common subsequence. Indexes in the changelog are positions in the slice at the time each
+// change is applied, so the changelog can be patched in order. A deletion followed by an insertion at
+// the same position is reported as an update of that element
+func (d *Differ) diffSliceLCS(path []string, a, b reflect.Value) error {
+	ops := lcs(a, b)
+
+	changed := false
+	for _, op := range ops {
+		if op.t != lcsEqual {
+			changed = true
+			break
+		}
+	}
+
+	if changed && d.ReorderAsWhole && d.reordered(a, b) {
+		d.cl.Add(UPDATE, path, exportInterface(a), exportInterface(b))
+		return nil
+	}
+
+	parent := exportInterface(a)
+	pos := 0
+
+	for i := 0; i < len(ops); {
+		if ops[i].t == lcsEqual {
+			if d.IncludeUnchanged {
+				err := d.diff(copyAppend(path, strconv.Itoa(pos)), a.Index(ops[i].a), b.Index(ops[i].b), parent)
+				if err != nil {
+					return err
+				}
+			}
+			pos++
+			i++
+			continue
+		}
+
+		// gather the run of deletions and insertions between two equal elements
+		var dels, ins []int
+		for ; i < len(ops) && ops[i].t != lcsEqual; i++ {
+			if ops[i].t == lcsDelete {
+				dels = append(dels, ops[i].a)
+			} else {
+				ins = append(ins, ops[i].b)
+			}
+		}
+
+		for len(dels) > 0 && len(ins) > 0 {
+			err := d.diff(copyAppend(path, strconv.Itoa(pos)), a.Index(dels[0]), b.Index(ins[0]), parent)
+			if err != nil {
+				return err
+			}
+			dels, ins = dels[1:], ins[1:]
+			pos++
+		}
+
+		for _, x := range dels {
+			d.cl.Add(DELETE, copyAppend(path, strconv.Itoa(pos)), exportInterface(a.Index(x)), nil)
+		}
+
+		for _, x := range ins {
+			d.cl.Add(CREATE, copyAppend(path, strconv.Itoa(pos)), nil, exportInterface(b.Index(x)))
+			pos++
+		}
+	}
+
+	return nil
+}
+
+// lcsMaxCells limits the size of the table lcs builds, which holds an int for every pair of elements
+// that remain once the common prefix and suffix are trimmed
+const lcsMaxCells = 1 << 20
+
+// lcs computes an edit script between a and b from their longest common subsequence. When the
+// elements between the common prefix and suffix are too many to build the table for, they are
+// compared by index instead, as an ordered diff would
+func lcs(a, b reflect.Value) []lcsOp {
+	eq := func(i, j int) bool {
+		return reflect.DeepEqual(exportInterface(a.Index(i)), exportInterface(b.Index(j)))
+	}
+
+	n, m := a.Len(), b.Len()
+
+	// trim the common prefix and suffix, which are often the bulk of the slice
+	start := 0
+	for start < n && start < m && eq(start, start) {
+		start++
+	}
+
+	end := 0
+	for end < n-start && end < m-start && eq(n-1-end, m-1-end) {
+		end++
+	}
+
+	rn, rm := n-start-end, m-start-end
+
+	ops := make([]lcsOp, 0, n+m)
+	for i := 0; i < start; i++ {
+		ops = append(ops, lcsOp{lcsEqual, i, i})
+	}
+
+	if rn*rm > lcsMaxCells {
+		// deletions followed by insertions are paired up into updates by index
+		for i := 0; i < rn; i++ {
+			ops = append(ops, lcsOp{lcsDelete, start + i, -1})
+		}
+		for j := 0; j < rm; j++ {
+			ops = append(ops, lcsOp{lcsInsert, -1, start + j})
+		}
+		return appendLCSSuffix(ops, n, m, end)
+	}
+
+	// table[i][j] holds the length of the lcs of a[start+i:] and b[start+j:]
+	table := make([][]int, rn+1)
+	for i := range table {
+		table[i] = make([]int, rm+1)
+	}
+
+	for i := rn - 1; i >= 0; i-- {
+		for j := rm - 1; j >= 0; j-- {
+			switch {
+			case eq(start+i, start+j):
+				table[i][j] = table[i+1][j+1] + 1
+			case table[i+1][j] >= table[i][j+1]:
+				table[i][j] = table[i+1][j]
+			default:
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < rn || j < rm {
+		switch {
+		case i < rn && j < rm && eq(start+i, start+j):
+			ops = append(ops, lcsOp{lcsEqual, start + i, start + j})
+			i++
+			j++
+		case j == rm || (i < rn && table[i+1][j] >= table[i][j+1]):
+			ops = append(ops, lcsOp{lcsDelete, start + i, -1})
+			i++
+		default:
+			ops = append(ops, lcsOp{lcsInsert, -1, start + j})
+			j++
+		}
+	}
+
+	return appendLCSSuffix(ops, n, m, end)
+}
+
+// appendLCSSuffix appends the common suffix of the last end elements of both slices to ops
+func appendLCSSuffix(ops []lcsOp, n, m, end int) []lcsOp {
+	for k := 0; k < end; k++ {
+		ops = append(ops, lcsOp{lcsEqual, n - end + k, m - end + k})
+	}
+
+	return ops
+}
@@ -0,0 +1,100 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"reflect"
+	"time"
+)
+
+// DiffStream compares a and b like Diff, but passes each change to emit as soon as it is found,
+// rather than collecting all of them in a changelog. Diffing stops at the first error returned
+// by emit, which is then returned. With EmitEndMarker enabled, a final END change is emitted
+// once the diff has completed
+func (d *Differ) DiffStream(a, b interface{}, emit func(Change) error) error {
+	if d.ReversePerspective {
+		a, b = b, a
+	}
+
+	if !d.EmitEndMarker {
+		return d.stream([]string{}, reflect.ValueOf(a), reflect.ValueOf(b), emit)
+	}
+
+	var sum StreamSummary
+	start := time.Now()
+
+	err := d.stream([]string{}, reflect.ValueOf(a), reflect.ValueOf(b), func(c Change) error {
+		sum.Stats.add(c)
+		return emit(c)
+	})
+	if err != nil {
+		return err
+	}
+
+	sum.Duration = time.Since(start)
+
+	return emit(Change{Type: END, Path: []string{}, To: sum})
+}
+
+func (d *Differ) stream(path []string, a, b reflect.Value, emit func(Change) error) error {
+	// reset the state of the diff
+	d.cl = Changelog{}
+	d.emit = emit
+	d.held = 0
+	defer func() { d.emit = nil }()
+
+	err := d.diff(path, a, b, nil)
+	if err != nil {
+		return err
+	}
+
+	return d.flush()
+}
+
+// CountChanges returns the number of changes between a and b, without collecting them in a
+// changelog. Unchanged values and warnings aren't counted
+func CountChanges(a, b interface{}, opts ...func(d *Differ) error) (int, error) {
+	d, err := NewDiffer(opts...)
+	if err != nil {
+		return 0, err
+	}
+	return d.CountChanges(a, b)
+}
+
+// CountChanges returns the number of changes between a and b, without collecting them in a
+// changelog. Unchanged values and warnings aren't counted
+func (d *Differ) CountChanges(a, b interface{}) (int, error) {
+	var n int
+
+	err := d.DiffStream(a, b, func(c Change) error {
+		if c.Type != EQUAL && c.Type != WARNING && c.Type != END {
+			n++
+		}
+		return nil
+	})
+
+	return n, err
+}
+
+// flush emits the changes found so far. Changes that may still be altered, such as the fields of
+// a struct that may be replaced as a whole, are held back until they are complete
+func (d *Differ) flush() error {
+	if len(d.cl) == 0 {
+		return nil
+	}
+
+	d.finish(d.cl)
+
+	for _, c := range d.cl {
+		err := d.emit(c)
+		if err != nil {
+			return err
+		}
+	}
+
+	d.cl = d.cl[:0]
+
+	return nil
+}
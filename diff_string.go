@@ -7,6 +7,10 @@ package diff
 import "reflect"
 
 func (d *Differ) diffString(path []string, a, b reflect.Value, parent interface{}) error {
+	if AreType(a, b, jsonNumberType) {
+		return d.diffJSONNumber(path, a, b, parent)
+	}
+
 	if a.Kind() == reflect.Invalid {
 		d.cl.Add(CREATE, path, nil, exportInterface(b))
 		return nil
@@ -21,14 +25,28 @@ func (d *Differ) diffString(path []string, a, b reflect.Value, parent interface{
 		return ErrTypeMismatch
 	}
 
-	if a.String() != b.String() {
-		if a.CanInterface() {
+	if a.String() != b.String() && !d.synonyms(a.String(), b.String()) {
+		if a.CanInterface() || d.IncludeUnexported {
 			// If a and/or b is of a type that is an alias for String, store that type in changelog
 			d.cl.Add(UPDATE, path, exportInterface(a), exportInterface(b), parent)
 		} else {
 			d.cl.Add(UPDATE, path, a.String(), b.String(), parent)
 		}
+	} else {
+		d.unchanged(path, a, b, parent)
 	}
 
 	return nil
 }
+
+// synonyms returns true if both strings belong to the same synonym group
+func (d *Differ) synonyms(a, b string) bool {
+	ga, ok := d.stringSynonyms[a]
+	if !ok {
+		return false
+	}
+
+	gb, ok := d.stringSynonyms[b]
+
+	return ok && ga == gb
+}
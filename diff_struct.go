@@ -6,6 +6,7 @@ package diff
 
 import (
 	"reflect"
+	"sync"
 	"time"
 )
 
@@ -14,6 +15,14 @@ func (d *Differ) diffStruct(path []string, a, b reflect.Value, parent interface{
 		return d.diffTime(path, a, b)
 	}
 
+	if isSyncMap(a, b) {
+		return d.diffSyncMap(path, a, b, parent)
+	}
+
+	if d.UntaggedStructsAtomic && (untagged(d.TagName, a) || untagged(d.TagName, b)) {
+		return d.diffAtomic(path, a, b, parent)
+	}
+
 	if a.Kind() == reflect.Invalid {
 		if d.DisableStructValues {
 			d.cl.Add(CREATE, path, nil, exportInterface(b))
@@ -30,9 +39,25 @@ func (d *Differ) diffStruct(path []string, a, b reflect.Value, parent interface{
 		return d.structValues(DELETE, path, a)
 	}
 
+	// the parent would leak the values of redacted fields
+	sparent := parent
+	parent = exportInterface(a)
+	if redacted(d.TagName, a.Type()) {
+		parent = nil
+	}
+
+	sstart := len(d.cl)
+	var total, changed int
+
+	// the changes to the fields may still be replaced by the whole struct
+	if d.StructWholeThreshold > 0 {
+		d.held++
+		defer func() { d.held-- }()
+	}
+
 	for i := 0; i < a.NumField(); i++ {
 		field := a.Type().Field(i)
-		tname := tagName(d.TagName, field)
+		tname := tagName(d.TagName, field, d.TagFallback...)
 
 		if tname == "-" || hasTagOption(d.TagName, field, "immutable") {
 			continue
@@ -45,8 +70,20 @@ func (d *Differ) diffStruct(path []string, a, b reflect.Value, parent interface{
 		af := a.Field(i)
 		bf := b.FieldByName(field.Name)
 
+		if diffIgnored(af) || diffIgnored(bf) {
+			continue
+		}
+
+		if d.OmitEmpty && hasTagOption(d.TagName, field, "omitempty") && isEmpty(af) && isEmpty(bf) {
+			continue
+		}
+
+		// a nil embedded pointer has no fields to flatten, so it is created or deleted as a whole
+		flatten := d.FlattenEmbeddedStructs && field.Anonymous
+		whole := flatten && nilEmbedded(af, bf)
+
 		fpath := path
-		if !(d.FlattenEmbeddedStructs && field.Anonymous) {
+		if !flatten || whole {
 			fpath = copyAppend(fpath, tname)
 		}
 
@@ -54,24 +91,203 @@ func (d *Differ) diffStruct(path []string, a, b reflect.Value, parent interface{
 			continue
 		}
 
-		// skip private fields
-		if !a.CanInterface() {
+		// skip the fields of structs held by private fields
+		if !a.CanInterface() && !d.IncludeUnexported {
 			continue
 		}
 
-		err := d.diff(fpath, af, bf, exportInterface(a))
+		start := len(d.cl)
+
+		redact := hasTagOption(d.TagName, field, "redact")
+		if redact {
+			d.held++
+		}
+
+		var err error
+		if whole {
+			d.diffEmbedded(fpath, af, bf)
+		} else if name, ok := tagOptionValue(d.TagName, field, "comparator"); ok {
+			err = d.diffCompared(name, fpath, af, bf, parent)
+		} else if name, ok := tagOptionValue(d.TagName, field, "differ"); ok {
+			err = d.diffNamed(name, fpath, af, bf, parent)
+		} else if d.SemanticJSON && hasTagOption(d.TagName, field, "json") && isBytes(af, bf) {
+			err = d.diffRawJSON(fpath, af, bf, parent)
+		} else {
+			err = d.diff(fpath, af, bf, parent)
+		}
+		if err != nil {
+			return err
+		}
+
+		if redact {
+			d.cl.redact(start)
+			d.held--
+		}
+
+		total++
+		if d.cl.changedSince(start) {
+			changed++
+		}
+	}
+
+	for _, cf := range d.computedFields {
+		av, bv := cf.fn(exportInterface(a)), cf.fn(exportInterface(b))
+		if av == nil && bv == nil {
+			continue
+		}
+
+		start := len(d.cl)
+
+		err := d.diff(copyAppend(path, cf.name), reflect.ValueOf(av), reflect.ValueOf(bv), parent)
 		if err != nil {
 			return err
 		}
+
+		total++
+		if d.cl.changedSince(start) {
+			changed++
+		}
+	}
+
+	// replace the changes to individual fields with the whole struct when most of it has changed
+	if d.StructWholeThreshold > 0 && total > 0 && float64(changed)/float64(total) > d.StructWholeThreshold {
+		if !redacted(d.TagName, a.Type()) {
+			d.cl = d.cl[:sstart]
+			d.cl.Add(UPDATE, path, exportInterface(a), exportInterface(b), sparent)
+		}
+	}
+
+	return nil
+}
+
+// computedField is a value derived from a struct, which is diffed as one of its fields
+type computedField struct {
+	name string
+	fn   func(interface{}) interface{}
+}
+
+// changedSince returns true if any change from start onwards isn't an EQUAL
+func (cl Changelog) changedSince(start int) bool {
+	for i := start; i < len(cl); i++ {
+		if cl[i].Type != EQUAL {
+			return true
+		}
+	}
+	return false
+}
+
+// diffNamed compares the values with the differ registered under name, regardless of their type
+func (d *Differ) diffNamed(name string, path []string, a, b reflect.Value, parent interface{}) error {
+	vd, ok := d.namedDiffers[name]
+	if !ok {
+		return NewErrorf("no differ registered as %s", name)
+	}
+
+	if d.DiscardParent {
+		parent = nil
+	}
+
+	diffType, diffFunc := d.getDiffType(a, b)
+
+	return vd.Diff(diffType, diffFunc, &d.cl, path, a, b, parent)
+}
+
+// diffCompared compares the values as a whole with the comparator registered under name
+func (d *Differ) diffCompared(name string, path []string, a, b reflect.Value, parent interface{}) error {
+	equal, ok := d.comparators[name]
+	if !ok {
+		return NewErrorf("no comparator registered as %s", name)
+	}
+
+	if d.DiscardParent {
+		parent = nil
+	}
+
+	av, bv := exportInterface(a), exportInterface(b)
+	if !equal(av, bv) {
+		d.cl.Add(UPDATE, path, av, bv, parent)
+	} else {
+		d.unchanged(path, a, b, parent)
+	}
+
+	return nil
+}
+
+// nilEmbedded returns true if exactly one of the values is a nil pointer
+func nilEmbedded(a, b reflect.Value) bool {
+	if a.Kind() != reflect.Ptr || b.Kind() != reflect.Ptr {
+		return false
+	}
+	return a.IsNil() != b.IsNil()
+}
+
+// diffEmbedded reports the creation or deletion of an embedded pointer
+func (d *Differ) diffEmbedded(path []string, a, b reflect.Value) {
+	if a.IsNil() {
+		d.cl.Add(CREATE, path, nil, exportInterface(b))
+		return
+	}
+	d.cl.Add(DELETE, path, exportInterface(a), nil)
+}
+
+// diffAtomic compares two values as a whole, reporting a single change if they differ
+func (d *Differ) diffAtomic(path []string, a, b reflect.Value, parent interface{}) error {
+	if a.Kind() == reflect.Invalid {
+		d.cl.Add(CREATE, path, nil, exportInterface(b))
+		return nil
+	}
+
+	if b.Kind() == reflect.Invalid {
+		d.cl.Add(DELETE, path, exportInterface(a), nil)
+		return nil
+	}
+
+	if !reflect.DeepEqual(exportInterface(a), exportInterface(b)) {
+		d.cl.Add(UPDATE, path, exportInterface(a), exportInterface(b), parent)
+	} else {
+		d.unchanged(path, a, b, parent)
 	}
 
 	return nil
 }
 
+// untagged returns true if none of the struct's fields carry the tag
+func untagged(tag string, v reflect.Value) bool {
+	if v.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		if _, ok := v.Type().Field(i).Tag.Lookup(tag); ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// diffIgnored returns true if the value implements DiffIgnored and has asked to be ignored
+func diffIgnored(v reflect.Value) bool {
+	if !v.IsValid() || !v.Type().Implements(diffIgnoredType) {
+		return false
+	}
+
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return false
+	}
+
+	return exportInterface(v).(DiffIgnored).DiffIgnore()
+}
+
 func (d *Differ) structValues(t string, path []string, a reflect.Value) error {
 	var nd Differ
 	nd.Filter = d.Filter
 	nd.customValueDiffers = d.customValueDiffers
+	nd.HexEncodeBytes = d.HexEncodeBytes
+	nd.IncludeUnchanged = d.IncludeUnchanged
+	nd.OmitZeroCreates = d.OmitZeroCreates
+	nd.TagName = d.TagName
+	nd.TagFallback = d.TagFallback
 
 	if t != CREATE && t != DELETE {
 		return ErrInvalidChangeType
@@ -87,10 +303,15 @@ func (d *Differ) structValues(t string, path []string, a reflect.Value) error {
 
 	x := reflect.New(a.Type()).Elem()
 
+	parent := exportInterface(a)
+	if redacted(d.TagName, a.Type()) {
+		parent = nil
+	}
+
 	for i := 0; i < a.NumField(); i++ {
 
 		field := a.Type().Field(i)
-		tname := tagName(d.TagName, field)
+		tname := tagName(d.TagName, field, d.TagFallback...)
 
 		if tname == "-" {
 			continue
@@ -103,21 +324,124 @@ func (d *Differ) structValues(t string, path []string, a reflect.Value) error {
 		af := a.Field(i)
 		xf := x.FieldByName(field.Name)
 
+		if diffIgnored(af) {
+			continue
+		}
+
 		fpath := copyAppend(path, tname)
 
 		if nd.Filter != nil && !nd.Filter(fpath, a.Type(), field) {
 			continue
 		}
 
-		err := nd.diff(fpath, xf, af, exportInterface(a))
+		start := len(nd.cl)
+
+		err := nd.diff(fpath, xf, af, parent)
 		if err != nil {
 			return err
 		}
+
+		if hasTagOption(d.TagName, field, "redact") {
+			nd.cl.redact(start)
+		}
 	}
 
 	for i := 0; i < len(nd.cl); i++ {
-		(d.cl) = append(d.cl, swapChange(t, nd.cl[i]))
+		c := swapChange(t, nd.cl[i])
+		if d.OmitZeroCreates && c.Type == CREATE && zeroValue(c.To) {
+			continue
+		}
+		(d.cl) = append(d.cl, c)
 	}
 
 	return nil
 }
+
+// zeroValue returns true if v is nil or the zero value of its type
+func zeroValue(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return !rv.IsValid() || rv.IsZero()
+}
+
+// isRedacted returns true if the values of the change were masked
+func (c Change) isRedacted() bool {
+	_, from := c.From.(Redacted)
+	_, to := c.To.(Redacted)
+	return c.Redacted || from || to
+}
+
+// redact masks the values of changes from start onwards, along with their parent
+func (cl Changelog) redact(start int) {
+	for i := start; i < len(cl); i++ {
+		if cl[i].From != nil {
+			cl[i].From = RedactedValue
+		}
+		if cl[i].To != nil {
+			cl[i].To = RedactedValue
+		}
+		cl[i].parent = nil
+		cl[i].Redacted = true
+	}
+}
+
+// redactedTypes caches whether types hold redacted fields, as they are checked for every struct diffed
+var redactedTypes = struct {
+	sync.RWMutex
+	m map[redactedType]bool
+}{
+	m: make(map[redactedType]bool),
+}
+
+type redactedType struct {
+	tag string
+	t   reflect.Type
+}
+
+// redacted returns true if the type holds a field tagged with redact at any depth
+func redacted(tag string, t reflect.Type) bool {
+	k := redactedType{tag, t}
+
+	redactedTypes.RLock()
+	r, ok := redactedTypes.m[k]
+	redactedTypes.RUnlock()
+
+	if ok {
+		return r
+	}
+
+	r = holdsRedacted(tag, t, nil)
+
+	redactedTypes.Lock()
+	redactedTypes.m[k] = r
+	redactedTypes.Unlock()
+
+	return r
+}
+
+func holdsRedacted(tag string, t reflect.Type, seen map[reflect.Type]bool) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return holdsRedacted(tag, t.Elem(), seen)
+	case reflect.Struct:
+	default:
+		return false
+	}
+
+	if seen[t] {
+		return false
+	}
+
+	if seen == nil {
+		seen = make(map[reflect.Type]bool)
+	}
+	seen[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if hasTagOption(tag, f, "redact") || holdsRedacted(tag, f.Type, seen) {
+			return true
+		}
+	}
+
+	return false
+}
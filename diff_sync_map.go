@@ -0,0 +1,59 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"reflect"
+	"sync"
+)
+
+var syncMapType = reflect.TypeOf(sync.Map{})
+
+func (d *Differ) diffSyncMap(path []string, a, b reflect.Value, parent interface{}) error {
+	ae, err := syncMapEntries(a)
+	if err != nil {
+		return err
+	}
+
+	be, err := syncMapEntries(b)
+	if err != nil {
+		return err
+	}
+
+	return d.diffMap(path, ae, be, parent)
+}
+
+// syncMapEntries takes a snapshot of the entries of a sync.Map, so that it can be diffed like a regular
+// map. Entries stored or deleted concurrently while taking the snapshot may or may not be included.
+// Values that are not addressable, such as fields of a struct passed by value, can't be read without
+// copying the lock that guards them, and return ErrUnaddressableSyncMap
+func syncMapEntries(v reflect.Value) (reflect.Value, error) {
+	if v.Kind() == reflect.Invalid {
+		return v, nil
+	}
+
+	if !v.CanAddr() {
+		return v, ErrUnaddressableSyncMap
+	}
+
+	sm := exportInterface(v.Addr()).(*sync.Map)
+
+	entries := make(map[interface{}]interface{})
+	sm.Range(func(k, v interface{}) bool {
+		entries[k] = v
+		return true
+	})
+
+	return reflect.ValueOf(entries), nil
+}
+
+func isSyncMap(a, b reflect.Value) bool {
+	for _, v := range []reflect.Value{a, b} {
+		if v.Kind() == reflect.Struct && v.Type() == syncMapType {
+			return true
+		}
+	}
+	return false
+}
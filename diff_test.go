@@ -5,7 +5,17 @@
 package diff_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"net"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -424,7 +434,7 @@ func TestDiff(t *testing.T) {
 		{
 			"struct-time-update", tstruct{}, tstruct{Time: currentTime},
 			diff.Changelog{
-				diff.Change{Type: diff.UPDATE, Path: []string{"time"}, From: time.Time{}, To: currentTime},
+				diff.Change{Type: diff.UPDATE, Path: []string{"time"}, From: time.Time{}, To: currentTime.Round(0)},
 			},
 			nil,
 		},
@@ -740,6 +750,245 @@ func TestDiffSliceOrdering(t *testing.T) {
 
 }
 
+func TestDiffReorderAsWhole(t *testing.T) {
+	cases := []struct {
+		Name      string
+		A, B      interface{}
+		Changelog diff.Changelog
+	}{
+		{
+			"int-slice-reverse", []int{1, 2, 3}, []int{3, 2, 1},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{}, From: []int{1, 2, 3}, To: []int{3, 2, 1}},
+			},
+		},
+		{
+			"nested-slice-reorder", map[string][]string{"a": {"1", "2"}}, map[string][]string{"a": {"2", "1"}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"a"}, From: []string{"1", "2"}, To: []string{"2", "1"}},
+			},
+		},
+		{
+			"int-slice-changed-element", []int{1, 2, 3}, []int{3, 2, 4},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"0"}, From: 1, To: 3},
+				diff.Change{Type: diff.UPDATE, Path: []string{"2"}, From: 3, To: 4},
+			},
+		},
+		{
+			"int-slice-duplicates", []int{1, 1, 2}, []int{2, 1, 2},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"0"}, From: 1, To: 2},
+			},
+		},
+		{
+			"int-slice-unchanged", []int{1, 2, 3}, []int{1, 2, 3},
+			diff.Changelog{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			d, err := diff.NewDiffer(diff.SliceOrdering(true), diff.ReorderAsWhole(true))
+			require.Nil(t, err)
+			cl, err := d.Diff(tc.A, tc.B)
+			require.Nil(t, err)
+			require.Equal(t, len(tc.Changelog), len(cl))
+
+			for i, c := range cl {
+				assert.Equal(t, tc.Changelog[i].Type, c.Type)
+				assert.Equal(t, tc.Changelog[i].Path, c.Path)
+				assert.Equal(t, tc.Changelog[i].From, c.From)
+				assert.Equal(t, tc.Changelog[i].To, c.To)
+			}
+		})
+	}
+
+	t.Run("patch", func(t *testing.T) {
+		a := []int{1, 2, 3}
+		d, err := diff.NewDiffer(diff.SliceOrdering(true), diff.ReorderAsWhole(true))
+		require.Nil(t, err)
+		cl, err := d.Diff(a, []int{3, 2, 1})
+		require.Nil(t, err)
+
+		pl := diff.Patch(cl, &a)
+		assert.False(t, pl.HasErrors())
+		assert.Equal(t, []int{3, 2, 1}, a)
+	})
+}
+
+func TestDiffSliceLCS(t *testing.T) {
+	cases := []struct {
+		Name      string
+		A, B      interface{}
+		Changelog diff.Changelog
+	}{
+		{
+			"int-slice-insert-middle", []int{1, 2, 4}, []int{1, 2, 3, 4},
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"2"}, To: 3},
+			},
+		},
+		{
+			"int-slice-delete-middle", []int{1, 2, 3, 4}, []int{1, 3, 4},
+			diff.Changelog{
+				diff.Change{Type: diff.DELETE, Path: []string{"1"}, From: 2},
+			},
+		},
+		{
+			"int-slice-replace", []int{1, 2, 3}, []int{1, 5, 3},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"1"}, From: 2, To: 5},
+			},
+		},
+		{
+			"int-slice-insert-front-delete-back", []int{1, 2, 3}, []int{0, 1, 2},
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"0"}, To: 0},
+				diff.Change{Type: diff.DELETE, Path: []string{"3"}, From: 3},
+			},
+		},
+		{
+			"string-slice-multiple-inserts", []string{"a", "c", "e"}, []string{"a", "b", "c", "d", "e"},
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"1"}, To: "b"},
+				diff.Change{Type: diff.CREATE, Path: []string{"3"}, To: "d"},
+			},
+		},
+		{
+			"struct-slice-insert", []tmstruct{{"a", 1}, {"c", 3}}, []tmstruct{{"a", 1}, {"b", 2}, {"c", 3}},
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"1"}, To: tmstruct{"b", 2}},
+			},
+		},
+		{
+			"struct-slice-update", []tmstruct{{"a", 1}, {"b", 2}}, []tmstruct{{"a", 1}, {"b", 3}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"1", "bar"}, From: 2, To: 3},
+			},
+		},
+		{
+			"int-slice-unchanged", []int{1, 2, 3}, []int{1, 2, 3},
+			diff.Changelog{},
+		},
+		{
+			"map-of-slices-inserts",
+			map[string][]string{"x": {"a", "b", "c"}, "y": {"1", "2"}},
+			map[string][]string{"x": {"a", "z", "b", "c"}, "y": {"0", "1", "2", "3"}},
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"x", "1"}, To: "z"},
+				diff.Change{Type: diff.CREATE, Path: []string{"y", "0"}, To: "0"},
+				diff.Change{Type: diff.CREATE, Path: []string{"y", "3"}, To: "3"},
+			},
+		},
+		{
+			"map-of-slices-insert-delete",
+			map[string][]int{"x": {1, 2, 3, 4}},
+			map[string][]int{"x": {0, 1, 3, 4}},
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"x", "0"}, To: 0},
+				diff.Change{Type: diff.DELETE, Path: []string{"x", "2"}, From: 2},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			d, err := diff.NewDiffer(diff.SliceOrdering(true), diff.SliceLCS(true))
+			require.Nil(t, err)
+			cl, err := d.Diff(tc.A, tc.B)
+			require.Nil(t, err)
+			require.Equal(t, len(tc.Changelog), len(cl))
+
+			for i, c := range cl {
+				assert.Equal(t, tc.Changelog[i].Type, c.Type)
+				assert.Equal(t, tc.Changelog[i].Path, c.Path)
+				assert.Equal(t, tc.Changelog[i].From, c.From)
+				assert.Equal(t, tc.Changelog[i].To, c.To)
+			}
+
+			target := reflect.New(reflect.TypeOf(tc.A))
+			target.Elem().Set(reflect.ValueOf(tc.A))
+			pl := d.Patch(cl, target.Interface())
+			assert.False(t, pl.HasErrors())
+			assert.Equal(t, tc.B, target.Elem().Interface())
+		})
+	}
+
+	t.Run("randomized", func(t *testing.T) {
+		r := rand.New(rand.NewSource(1))
+
+		for n := 0; n < 20; n++ {
+			a := make([]int, 200)
+			for i := range a {
+				a[i] = r.Intn(1000)
+			}
+
+			// apply a handful of random edits, so the optimal edit script has at most that many changes
+			b := append([]int{}, a...)
+			edits := 1 + r.Intn(8)
+			for e := 0; e < edits; e++ {
+				i := r.Intn(len(b))
+				if r.Intn(2) == 0 {
+					b = append(b[:i], b[i+1:]...)
+				} else {
+					b = append(b[:i], append([]int{r.Intn(1000)}, b[i:]...)...)
+				}
+			}
+
+			d, err := diff.NewDiffer(diff.SliceOrdering(true), diff.SliceLCS(true))
+			require.Nil(t, err)
+			cl, err := d.Diff(a, b)
+			require.Nil(t, err)
+			assert.LessOrEqual(t, len(cl), edits)
+
+			pl := d.Patch(cl, &a)
+			assert.False(t, pl.HasErrors())
+			assert.Equal(t, b, a)
+		}
+	})
+
+	t.Run("large", func(t *testing.T) {
+		// too many differing elements to build the table for, so they are compared by index
+		a := make([]int, 10000)
+		b := make([]int, 10005)
+		for i := range a {
+			a[i] = i
+		}
+		for i := range b {
+			b[i] = i + 1
+		}
+
+		d, err := diff.NewDiffer(diff.SliceOrdering(true), diff.SliceLCS(true))
+		require.Nil(t, err)
+		cl, err := d.Diff(a, b)
+		require.Nil(t, err)
+		require.Len(t, cl, 10005)
+		assert.Equal(t, diff.Change{Type: diff.UPDATE, Path: []string{"0"}, From: 0, To: 1}, cl[0])
+		assert.Equal(t, diff.Change{Type: diff.CREATE, Path: []string{"10004"}, To: 10005}, cl[len(cl)-1])
+
+		pl := d.Patch(cl, &a)
+		assert.False(t, pl.HasErrors())
+		assert.Equal(t, b, a)
+
+		// deletions are reported at the same index, as each shifts the following elements
+		c := make([]int, 9990)
+		for i := range c {
+			c[i] = i
+		}
+
+		cl, err = d.Diff(b, c)
+		require.Nil(t, err)
+		require.Len(t, cl, 10005)
+		assert.Equal(t, diff.Change{Type: diff.DELETE, Path: []string{"9990"}, From: 9991}, cl[9990])
+		assert.Equal(t, diff.Change{Type: diff.DELETE, Path: []string{"9990"}, From: 10005}, cl[len(cl)-1])
+
+		pl = d.Patch(cl, &b)
+		assert.False(t, pl.HasErrors())
+		assert.Equal(t, c, b)
+	})
+}
+
 func TestFilter(t *testing.T) {
 	cases := []struct {
 		Name     string
@@ -750,88 +999,3805 @@ func TestFilter(t *testing.T) {
 		{"regex", []string{"item-*"}, [][]string{{"item-1", "subitem"}, {"item-2", "subitem"}}},
 	}
 
-	cl := diff.Changelog{
-		{Path: []string{"item-1", "subitem"}},
-		{Path: []string{"item-2", "subitem"}},
+	cl := diff.Changelog{
+		{Path: []string{"item-1", "subitem"}},
+		{Path: []string{"item-2", "subitem"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ncl := cl.Filter(tc.Filter)
+			assert.Len(t, ncl, len(tc.Expected))
+			for i, e := range tc.Expected {
+				assert.Equal(t, e, ncl[i].Path)
+			}
+		})
+	}
+}
+
+func TestFilterOut(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Filter   []string
+		Expected [][]string
+	}{
+		{"simple", []string{"item-1", "subitem"}, [][]string{{"item-2", "subitem"}}},
+		{"regex", []string{"item-*"}, [][]string{}},
+	}
+
+	cl := diff.Changelog{
+		{Path: []string{"item-1", "subitem"}},
+		{Path: []string{"item-2", "subitem"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ncl := cl.FilterOut(tc.Filter)
+			assert.Len(t, ncl, len(tc.Expected))
+			for i, e := range tc.Expected {
+				assert.Equal(t, e, ncl[i].Path)
+			}
+		})
+	}
+}
+
+func TestFilterFunc(t *testing.T) {
+	cl := diff.Changelog{
+		{Type: diff.UPDATE, Path: []string{"name"}, From: "one", To: "two"},
+		{Type: diff.UPDATE, Path: []string{"value"}, From: 1, To: 2},
+		{Type: diff.CREATE, Path: []string{"values", "0"}, To: "one"},
+		{Type: diff.UPDATE, Path: []string{"nested", "foo"}, From: "a", To: "b"},
+	}
+
+	ncl := cl.FilterFunc(func(c diff.Change) bool {
+		_, ok := c.To.(string)
+		return c.Type == diff.UPDATE && ok
+	})
+
+	require.Len(t, ncl, 2)
+	assert.Equal(t, []string{"name"}, ncl[0].Path)
+	assert.Equal(t, []string{"nested", "foo"}, ncl[1].Path)
+
+	ncl = ncl.FilterOut([]string{"nested"})
+	require.Len(t, ncl, 1)
+	assert.Equal(t, []string{"name"}, ncl[0].Path)
+}
+
+func TestRewritePaths(t *testing.T) {
+	cases := []struct {
+		Name     string
+		From, To []string
+		Expected [][]string
+	}{
+		{"prefix", []string{"details"}, []string{"meta"}, [][]string{{"meta", "status"}, {"meta", "attributes", "a"}, {"name"}, {"detailsx"}}},
+		{"multi-segment", []string{"details", "attributes"}, []string{"attrs"}, [][]string{{"details", "status"}, {"attrs", "a"}, {"name"}, {"detailsx"}}},
+		{"partial-segment", []string{"detail"}, []string{"meta"}, [][]string{{"details", "status"}, {"details", "attributes", "a"}, {"name"}, {"detailsx"}}},
+		{"no-match", []string{"missing"}, []string{"meta"}, [][]string{{"details", "status"}, {"details", "attributes", "a"}, {"name"}, {"detailsx"}}},
+		{"longer-than-path", []string{"name", "first"}, []string{"meta"}, [][]string{{"details", "status"}, {"details", "attributes", "a"}, {"name"}, {"detailsx"}}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl := diff.Changelog{
+				{Path: []string{"details", "status"}},
+				{Path: []string{"details", "attributes", "a"}},
+				{Path: []string{"name"}},
+				{Path: []string{"detailsx"}},
+			}
+
+			ncl := cl.RewritePaths(tc.From, tc.To)
+			require.Len(t, ncl, len(tc.Expected))
+			for i, e := range tc.Expected {
+				assert.Equal(t, e, ncl[i].Path)
+			}
+
+			// the original changelog is left untouched
+			assert.Equal(t, []string{"details", "status"}, cl[0].Path)
+		})
+	}
+}
+
+func TestStructValues(t *testing.T) {
+	cases := []struct {
+		Name       string
+		ChangeType string
+		X          interface{}
+		Changelog  diff.Changelog
+		Error      error
+	}{
+		{
+			"struct-create", diff.CREATE, tstruct{ID: "xxxxx", Name: "something", Value: 1, Values: []string{"one", "two", "three"}},
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"id"}, From: nil, To: "xxxxx"},
+				diff.Change{Type: diff.CREATE, Path: []string{"name"}, From: nil, To: "something"},
+				diff.Change{Type: diff.CREATE, Path: []string{"value"}, From: nil, To: 1},
+				diff.Change{Type: diff.CREATE, Path: []string{"values", "0"}, From: nil, To: "one"},
+				diff.Change{Type: diff.CREATE, Path: []string{"values", "1"}, From: nil, To: "two"},
+				diff.Change{Type: diff.CREATE, Path: []string{"values", "2"}, From: nil, To: "three"},
+			},
+			nil,
+		},
+		{
+			"struct-delete", diff.DELETE, tstruct{ID: "xxxxx", Name: "something", Value: 1, Values: []string{"one", "two", "three"}},
+			diff.Changelog{
+				diff.Change{Type: diff.DELETE, Path: []string{"id"}, From: "xxxxx", To: nil},
+				diff.Change{Type: diff.DELETE, Path: []string{"name"}, From: "something", To: nil},
+				diff.Change{Type: diff.DELETE, Path: []string{"value"}, From: 1, To: nil},
+				diff.Change{Type: diff.DELETE, Path: []string{"values", "0"}, From: "one", To: nil},
+				diff.Change{Type: diff.DELETE, Path: []string{"values", "1"}, From: "two", To: nil},
+				diff.Change{Type: diff.DELETE, Path: []string{"values", "2"}, From: "three", To: nil},
+			},
+			nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.StructValues(tc.ChangeType, []string{}, tc.X)
+
+			assert.Equal(t, tc.Error, err)
+			assert.Equal(t, len(tc.Changelog), len(cl))
+
+			for i, c := range cl {
+				assert.Equal(t, tc.Changelog[i].Type, c.Type)
+				assert.Equal(t, tc.Changelog[i].Path, c.Path)
+				assert.Equal(t, tc.Changelog[i].From, c.From)
+				assert.Equal(t, tc.Changelog[i].To, c.To)
+			}
+		})
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	type item struct {
+		Name  string            `diff:"name"`
+		Tags  []string          `diff:"tags"`
+		Attrs map[string]int    `diff:"attrs"`
+		Child *item             `diff:"child"`
+		Data  []byte            `diff:"data"`
+		Skip  string            `diff:"-"`
+		Meta  map[int][]float64 `diff:"meta"`
+	}
+
+	type inventory struct {
+		Owner string            `diff:"owner"`
+		Items map[string][]item `diff:"items"`
+		Empty []item            `diff:"empty"`
+	}
+
+	original := inventory{
+		Owner: "me",
+		Items: map[string][]item{
+			"tools": {
+				{
+					Name:  "hammer",
+					Tags:  []string{"heavy", "steel"},
+					Attrs: map[string]int{"weight": 3},
+					Child: &item{Name: "handle", Data: []byte{1, 2}},
+					Skip:  "skipped",
+					Meta:  map[int][]float64{1: {0.5, 1.5}},
+				},
+			},
+			"misc": {},
+		},
+		Empty: []item{},
+	}
+
+	cl, err := diff.Snapshot(original)
+	require.Nil(t, err)
+
+	for _, c := range cl {
+		assert.Equal(t, diff.CREATE, c.Type)
+	}
+	assert.Contains(t, cl, diff.Change{Type: diff.CREATE, Path: []string{"items", "tools", "0", "tags", "1"}, To: "steel"})
+	assert.Contains(t, cl, diff.Change{Type: diff.CREATE, Path: []string{"items", "tools", "0", "child", "name"}, To: "handle"})
+	assert.Contains(t, cl, diff.Change{Type: diff.CREATE, Path: []string{"items", "misc"}, To: []item{}})
+
+	var reconstructed inventory
+	pl := diff.Reconstruct(cl, &reconstructed)
+	require.False(t, pl.HasErrors())
+
+	expected := original
+	expected.Items["tools"][0].Skip = ""
+	assert.Equal(t, expected, reconstructed)
+
+	// the reconstructed value does not share memory with the original
+	reconstructed.Items["tools"][0].Child.Data[0] = 9
+	reconstructed.Items["tools"][0].Tags[0] = "light"
+	assert.Equal(t, byte(1), original.Items["tools"][0].Child.Data[0])
+	assert.Equal(t, "heavy", original.Items["tools"][0].Tags[0])
+
+	for _, v := range []interface{}{1, "value", []int{1, 2, 3}, map[string]bool{"a": true}} {
+		cl, err := diff.Snapshot(v)
+		require.Nil(t, err)
+
+		target := reflect.New(reflect.TypeOf(v))
+		pl := diff.Reconstruct(cl, target.Interface())
+		require.False(t, pl.HasErrors())
+		assert.Equal(t, v, target.Elem().Interface())
+	}
+}
+
+func TestDifferReuse(t *testing.T) {
+	d, err := diff.NewDiffer()
+	require.Nil(t, err)
+
+	cl, err := d.Diff([]string{"1", "2", "3"}, []string{"1"})
+	require.Nil(t, err)
+
+	require.Len(t, cl, 2)
+
+	assert.Equal(t, "2", cl[0].From)
+	assert.Equal(t, nil, cl[0].To)
+	assert.Equal(t, "3", cl[1].From)
+	assert.Equal(t, nil, cl[1].To)
+
+	cl, err = d.Diff([]string{"a", "b"}, []string{"a", "c"})
+	require.Nil(t, err)
+
+	require.Len(t, cl, 1)
+
+	assert.Equal(t, "b", cl[0].From)
+	assert.Equal(t, "c", cl[0].To)
+}
+
+type cancellingDiffer struct {
+	calls  int
+	after  int
+	cancel context.CancelFunc
+}
+
+func (o *cancellingDiffer) InsertParentDiffer(dfunc func(path []string, a, b reflect.Value, p interface{}) error) {
+}
+
+func (o *cancellingDiffer) Match(a, b reflect.Value) bool {
+	return diff.AreType(a, b, reflect.TypeOf(0))
+}
+
+func (o *cancellingDiffer) Diff(dt diff.DiffType, df diff.DiffFunc, cl *diff.Changelog, path []string, a, b reflect.Value, parent interface{}) error {
+	o.calls++
+	if o.calls == o.after {
+		o.cancel()
+	}
+	return df(path, a, b, parent)
+}
+
+func TestDiffContext(t *testing.T) {
+	a := make([]tistruct, 10000)
+	b := make([]tistruct, 10000)
+	for i := range a {
+		a[i] = tistruct{Name: strconv.Itoa(i), Value: i}
+		b[i] = tistruct{Name: strconv.Itoa(i), Value: i + 1}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cd := &cancellingDiffer{after: 100, cancel: cancel}
+	d, err := diff.NewDiffer(diff.CustomValueDiffers(cd))
+	require.Nil(t, err)
+
+	_, err = d.DiffContext(ctx, a, b)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 100, cd.calls)
+
+	// the differ can be reused once the context has been cancelled
+	cl, err := d.Diff(a[:10], b[:10])
+	require.Nil(t, err)
+	assert.Len(t, cl, 10)
+
+	_, err = d.DiffContext(ctx, 1, 2)
+	assert.Equal(t, context.Canceled, err)
+}
+
+// centsDiffer compares float amounts to the nearest cent
+type centsDiffer struct {
+	calls int
+}
+
+func (o *centsDiffer) InsertParentDiffer(dfunc func(path []string, a, b reflect.Value, p interface{}) error) {
+}
+
+func (o *centsDiffer) Match(a, b reflect.Value) bool {
+	return false
+}
+
+func (o *centsDiffer) Diff(dt diff.DiffType, df diff.DiffFunc, cl *diff.Changelog, path []string, a, b reflect.Value, parent interface{}) error {
+	o.calls++
+	if math.Round(a.Float()*100) != math.Round(b.Float()*100) {
+		cl.Add(diff.UPDATE, path, a.Interface(), b.Interface())
+	}
+	return nil
+}
+
+func TestDiffNamedDiffer(t *testing.T) {
+	type invoice struct {
+		Amount float64 `diff:"amount,differ=money"`
+		Rate   float64 `diff:"rate"`
+	}
+
+	cd := &centsDiffer{}
+	d, err := diff.NewDiffer(diff.RegisterNamedDiffer("money", cd), diff.DiscardComplexOrigin())
+	require.Nil(t, err)
+
+	cl, err := d.Diff(invoice{Amount: 1.001, Rate: 0.001}, invoice{Amount: 1.002, Rate: 0.002})
+	require.Nil(t, err)
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.UPDATE, Path: []string{"rate"}, From: 0.001, To: 0.002},
+	}, cl)
+	assert.Equal(t, 1, cd.calls)
+
+	cl, err = d.Diff(invoice{Amount: 1.00}, invoice{Amount: 1.01})
+	require.Nil(t, err)
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.UPDATE, Path: []string{"amount"}, From: 1.00, To: 1.01},
+	}, cl)
+
+	_, err = diff.Diff(invoice{Amount: 1}, invoice{Amount: 2})
+	assert.NotNil(t, err)
+}
+
+func TestDiffComparator(t *testing.T) {
+	type player struct {
+		Name  string  `diff:"name,comparator=caseless"`
+		Score float64 `diff:"score,comparator=approx"`
+		Rank  float64 `diff:"rank"`
+	}
+
+	approx := func(a, b interface{}) bool {
+		return math.Abs(a.(float64)-b.(float64)) < 0.01
+	}
+
+	caseless := func(a, b interface{}) bool {
+		return strings.EqualFold(a.(string), b.(string))
+	}
+
+	d, err := diff.NewDiffer(
+		diff.RegisterComparator("approx", approx),
+		diff.RegisterComparator("caseless", caseless),
+		diff.DiscardComplexOrigin(),
+	)
+	require.Nil(t, err)
+
+	cases := []struct {
+		Name      string
+		A, B      player
+		Changelog diff.Changelog
+	}{
+		{
+			"equal", player{"Ann", 1.001, 1}, player{"ANN", 1.002, 1},
+			diff.Changelog{},
+		},
+		{
+			"different", player{"Ann", 1, 1}, player{"Bob", 2, 1.001},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"name"}, From: "Ann", To: "Bob"},
+				diff.Change{Type: diff.UPDATE, Path: []string{"score"}, From: 1.0, To: 2.0},
+				diff.Change{Type: diff.UPDATE, Path: []string{"rank"}, From: 1.0, To: 1.001},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := d.Diff(tc.A, tc.B)
+			require.Nil(t, err)
+			assert.Equal(t, tc.Changelog, cl)
+		})
+	}
+
+	_, err = diff.Diff(player{}, player{})
+	assert.NotNil(t, err)
+}
+
+type celsius float64
+
+// celsiusDiffer ignores changes of less than a degree
+type celsiusDiffer struct {
+	calls int
+}
+
+func (o *celsiusDiffer) InsertParentDiffer(dfunc func(path []string, a, b reflect.Value, p interface{}) error) {
+}
+
+func (o *celsiusDiffer) Match(a, b reflect.Value) bool {
+	return diff.AreType(a, b, reflect.TypeOf(celsius(0)))
+}
+
+func (o *celsiusDiffer) Diff(dt diff.DiffType, df diff.DiffFunc, cl *diff.Changelog, path []string, a, b reflect.Value, parent interface{}) error {
+	o.calls++
+	if math.Abs(a.Float()-b.Float()) >= 1 {
+		cl.Add(diff.UPDATE, path, a.Interface(), b.Interface())
+	}
+	return nil
+}
+
+func TestDiffCustomDifferThroughInterface(t *testing.T) {
+	type reading struct {
+		Value interface{} `diff:"value"`
+	}
+
+	cd := &celsiusDiffer{}
+	d, err := diff.NewDiffer(diff.CustomValueDiffers(cd))
+	require.Nil(t, err)
+
+	cl, err := d.Diff(reading{celsius(20.1)}, reading{celsius(20.5)})
+	require.Nil(t, err)
+	assert.Len(t, cl, 0)
+	assert.Equal(t, 1, cd.calls)
+
+	cl, err = d.Diff(reading{celsius(20)}, reading{celsius(22)})
+	require.Nil(t, err)
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.UPDATE, Path: []string{"value"}, From: celsius(20), To: celsius(22)},
+	}, cl)
+	assert.Equal(t, 2, cd.calls)
+
+	cl, err = d.Diff(map[string]interface{}{"t": celsius(1)}, map[string]interface{}{"t": celsius(1.5)})
+	require.Nil(t, err)
+	assert.Len(t, cl, 0)
+	assert.Equal(t, 3, cd.calls)
+
+	cl, err = d.Diff([]interface{}{celsius(1)}, []interface{}{celsius(1.5)})
+	require.Nil(t, err)
+	assert.Len(t, cl, 0)
+}
+
+func TestDiffingOptions(t *testing.T) {
+	d, err := diff.NewDiffer(diff.SliceOrdering(false))
+	require.Nil(t, err)
+
+	assert.False(t, d.SliceOrdering)
+
+	cl, err := d.Diff([]int{1, 2, 3}, []int{1, 3, 2})
+	require.Nil(t, err)
+
+	assert.Len(t, cl, 0)
+
+	d, err = diff.NewDiffer(diff.SliceOrdering(true))
+	require.Nil(t, err)
+
+	assert.True(t, d.SliceOrdering)
+
+	cl, err = d.Diff([]int{1, 2, 3}, []int{1, 3, 2})
+	require.Nil(t, err)
+
+	assert.Len(t, cl, 2)
+
+	// some other options..
+}
+
+type Level int
+
+func TestDiffNamedIntTypes(t *testing.T) {
+	type settings struct {
+		Timeout time.Duration `diff:"timeout"`
+		Level   Level         `diff:"level"`
+		backoff time.Duration
+	}
+
+	a := settings{Timeout: time.Second, Level: 1, backoff: time.Millisecond}
+	b := settings{Timeout: time.Minute, Level: 3, backoff: time.Second}
+
+	cl, err := diff.Diff(a, b)
+	require.Nil(t, err)
+	require.Len(t, cl, 3)
+
+	assert.Equal(t, time.Second, cl[0].From)
+	assert.Equal(t, time.Minute, cl[0].To)
+	assert.Equal(t, Level(1), cl[1].From)
+	assert.Equal(t, Level(3), cl[1].To)
+	assert.Equal(t, time.Millisecond, cl[2].From)
+	assert.Equal(t, time.Second, cl[2].To)
+
+	pl := diff.Patch(cl[:2], &a)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, time.Minute, a.Timeout)
+	assert.Equal(t, Level(3), a.Level)
+}
+
+func TestDiffJSONNumber(t *testing.T) {
+	cases := []struct {
+		Name    string
+		A, B    json.Number
+		Changed bool
+	}{
+		{"equal", "1", "1", false},
+		{"trailing-zero", "1", "1.0", false},
+		{"exponent", "1000", "1e3", false},
+		{"negative", "-0.50", "-0.5", false},
+		{"different", "1", "2", true},
+		{"fraction", "1.5", "1.50001", true},
+		{"invalid", "abc", "abd", true},
+		{"fraction-syntax", "1/2", "0.5", true},
+		{"hex-syntax", "0x10", "16", true},
+		{"leading-zero", "01", "1", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B)
+			require.Nil(t, err)
+
+			if !tc.Changed {
+				assert.Len(t, cl, 0)
+				return
+			}
+
+			require.Len(t, cl, 1)
+			assert.Equal(t, diff.UPDATE, cl[0].Type)
+			assert.Equal(t, tc.A, cl[0].From)
+			assert.Equal(t, tc.B, cl[0].To)
+		})
+	}
+
+	var a, b map[string]interface{}
+	da := json.NewDecoder(strings.NewReader(`{"price": 10, "qty": 1}`))
+	da.UseNumber()
+	require.Nil(t, da.Decode(&a))
+	db := json.NewDecoder(strings.NewReader(`{"price": 10.00, "qty": 2}`))
+	db.UseNumber()
+	require.Nil(t, db.Decode(&b))
+
+	cl, err := diff.Diff(a, b)
+	require.Nil(t, err)
+	require.Len(t, cl, 1)
+	assert.Equal(t, []string{"qty"}, cl[0].Path)
+}
+
+type Money struct {
+	Amount   int
+	Currency string
+}
+
+func (m Money) DiffEqual(other interface{}) bool {
+	o, ok := other.(Money)
+	if !ok {
+		return false
+	}
+	return m.Amount == o.Amount && strings.EqualFold(m.Currency, o.Currency)
+}
+
+func TestDiffComparable(t *testing.T) {
+	type account struct {
+		Name    string  `diff:"name"`
+		Balance Money   `diff:"balance"`
+		Limit   *Money  `diff:"limit"`
+		History []Money `diff:"history"`
+	}
+
+	cases := []struct {
+		Name      string
+		A, B      interface{}
+		Changelog diff.Changelog
+	}{
+		{
+			"equal-not-deep-equal", Money{10, "usd"}, Money{10, "USD"},
+			diff.Changelog{},
+		},
+		{
+			"different", Money{10, "usd"}, Money{20, "usd"},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{}, From: Money{10, "usd"}, To: Money{20, "usd"}},
+			},
+		},
+		{
+			"struct-field",
+			account{Name: "a", Balance: Money{10, "usd"}, Limit: &Money{5, "eur"}},
+			account{Name: "a", Balance: Money{10, "USD"}, Limit: &Money{5, "EUR"}},
+			diff.Changelog{},
+		},
+		{
+			"struct-field-changed",
+			account{Name: "a", Balance: Money{10, "usd"}},
+			account{Name: "a", Balance: Money{10, "gbp"}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"balance"}, From: Money{10, "usd"}, To: Money{10, "gbp"}},
+			},
+		},
+		{
+			"pointer-nil",
+			account{Name: "a"},
+			account{Name: "a", Limit: &Money{5, "eur"}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"limit"}, From: nil, To: &Money{5, "eur"}},
+			},
+		},
+		{
+			"slice",
+			account{History: []Money{{1, "usd"}, {2, "usd"}}},
+			account{History: []Money{{2, "USD"}, {1, "USD"}}},
+			diff.Changelog{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B)
+			require.Nil(t, err)
+			require.Equal(t, len(tc.Changelog), len(cl))
+
+			for i, c := range cl {
+				assert.Equal(t, tc.Changelog[i].Type, c.Type)
+				assert.Equal(t, tc.Changelog[i].Path, c.Path)
+				assert.Equal(t, tc.Changelog[i].From, c.From)
+				assert.Equal(t, tc.Changelog[i].To, c.To)
+			}
+		})
+	}
+}
+
+func TestDiffIncludeUnchanged(t *testing.T) {
+	a := tstruct{
+		Name:          "one",
+		Value:         1,
+		Values:        []string{"a", "b"},
+		Map:           map[string]string{"x": "1", "y": "2"},
+		Identifiables: []tistruct{{"one", 1}},
+		Nested:        tnstruct{Slice: []tmstruct{{"foo", 1}}},
+	}
+	b := tstruct{
+		Name:          "two",
+		Value:         1,
+		Values:        []string{"a", "c"},
+		Map:           map[string]string{"x": "1", "y": "3"},
+		Identifiables: []tistruct{{"one", 2}},
+		Nested:        tnstruct{Slice: []tmstruct{{"foo", 1}}},
+	}
+
+	expected, err := diff.Diff(a, b)
+	require.Nil(t, err)
+
+	cl, err := diff.Diff(a, b, diff.IncludeUnchanged(true))
+	require.Nil(t, err)
+
+	equal := cl.FilterFunc(func(c diff.Change) bool {
+		return c.Type == diff.EQUAL
+	})
+	changed := cl.FilterFunc(func(c diff.Change) bool {
+		return c.Type != diff.EQUAL
+	})
+
+	assert.Equal(t, expected, changed)
+
+	paths := make([][]string, len(equal))
+	for i, c := range equal {
+		assert.Equal(t, c.From, c.To)
+		paths[i] = c.Path
+	}
+
+	// id is immutable, so it is never compared
+	assert.Equal(t, [][]string{
+		{"value"},
+		{"bool"},
+		{"values", "0"},
+		{"map", "x"},
+		{"time"},
+		{"pointer"},
+		{"identifiables", "one", "name"},
+		{"nested", "slice", "0", "foo"},
+		{"nested", "slice", "0", "bar"},
+		{"private"},
+	}, paths)
+
+	pl := diff.Patch(cl, &a)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, b, a)
+}
+
+func TestDiffDoublePointer(t *testing.T) {
+	one, two := 1, 2
+	p1, p2 := &one, &two
+	var np *int
+
+	cases := []struct {
+		Name      string
+		A, B      **int
+		Changelog diff.Changelog
+	}{
+		{"both-outer-nil", nil, nil, diff.Changelog{}},
+		{"both-inner-nil", &np, &np, diff.Changelog{}},
+		{"equal", &p1, &p1, diff.Changelog{}},
+		{
+			"outer-nil", nil, &p1,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{}, From: nil, To: &p1},
+			},
+		},
+		{
+			"inner-nil", &np, &p1,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{}, From: nil, To: p1},
+			},
+		},
+		{
+			"inner-nil-removed", &p1, &np,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{}, From: p1, To: nil},
+			},
+		},
+		{
+			"neither-nil", &p1, &p2,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{}, From: 1, To: 2},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B)
+			require.Nil(t, err)
+			require.Equal(t, len(tc.Changelog), len(cl))
+
+			for i, c := range cl {
+				assert.Equal(t, tc.Changelog[i].Type, c.Type)
+				assert.Equal(t, tc.Changelog[i].Path, c.Path)
+				assert.Equal(t, tc.Changelog[i].From, c.From)
+				assert.Equal(t, tc.Changelog[i].To, c.To)
+			}
+		})
+	}
+}
+
+func TestDiffBytes(t *testing.T) {
+	type MyType struct {
+		Data []byte `diff:"data"`
+	}
+
+	cases := []struct {
+		Name      string
+		A, B      interface{}
+		Changelog diff.Changelog
+	}{
+		{
+			"update", []byte{1, 2, 3}, []byte{1, 4, 3, 5},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{}, From: []byte{1, 2, 3}, To: []byte{1, 4, 3, 5}},
+			},
+		},
+		{
+			"equal", []byte{1, 2, 3}, []byte{1, 2, 3},
+			diff.Changelog{},
+		},
+		{
+			"nil-and-empty", MyType{}, MyType{Data: []byte{}},
+			diff.Changelog{},
+		},
+		{
+			"struct-field", MyType{Data: []byte("abc")}, MyType{Data: []byte("abd")},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"data"}, From: []byte("abc"), To: []byte("abd")},
+			},
+		},
+		{
+			"struct-create", nil, &MyType{Data: []byte("abc")},
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"data"}, To: []byte("abc")},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B)
+			require.Nil(t, err)
+			require.Equal(t, len(tc.Changelog), len(cl))
+
+			for i, c := range cl {
+				assert.Equal(t, tc.Changelog[i].Type, c.Type)
+				assert.Equal(t, tc.Changelog[i].Path, c.Path)
+				assert.Equal(t, tc.Changelog[i].From, c.From)
+				assert.Equal(t, tc.Changelog[i].To, c.To)
+			}
+		})
+	}
+}
+
+func TestDiffMapDefault(t *testing.T) {
+	defaults := func(key interface{}) (interface{}, bool) {
+		switch key {
+		case "replicas":
+			return 1, true
+		case "enabled":
+			return true, true
+		}
+		return nil, false
+	}
+
+	cases := []struct {
+		Name      string
+		A, B      interface{}
+		Changelog diff.Changelog
+	}{
+		{
+			"missing-matches-default",
+			map[string]int{"replicas": 1, "port": 80},
+			map[string]int{"port": 80},
+			diff.Changelog{},
+		},
+		{
+			"missing-differs-from-default",
+			map[string]int{"port": 80},
+			map[string]int{"replicas": 3, "port": 80},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"replicas"}, From: 1, To: 3},
+			},
+		},
+		{
+			"no-default",
+			map[string]int{"port": 80},
+			map[string]int{},
+			diff.Changelog{
+				diff.Change{Type: diff.DELETE, Path: []string{"port"}, From: 80},
+			},
+		},
+		{
+			"interface-values",
+			map[string]interface{}{"enabled": true, "replicas": 1},
+			map[string]interface{}{},
+			diff.Changelog{},
+		},
+		{
+			"converted-default",
+			map[string]int64{},
+			map[string]int64{"replicas": 2},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"replicas"}, From: int64(1), To: int64(2)},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B, diff.MapDefault(defaults))
+			require.Nil(t, err)
+			require.Equal(t, len(tc.Changelog), len(cl))
+
+			for i, c := range cl {
+				assert.Equal(t, tc.Changelog[i].Type, c.Type)
+				assert.Equal(t, tc.Changelog[i].Path, c.Path)
+				assert.Equal(t, tc.Changelog[i].From, c.From)
+				assert.Equal(t, tc.Changelog[i].To, c.To)
+			}
+		})
+	}
+}
+
+type transientCache struct {
+	Entries   map[string]string
+	Transient bool
+}
+
+func (c transientCache) DiffIgnore() bool {
+	return c.Transient
+}
+
+type lazyValue struct {
+	Value  string
+	Loaded bool
+}
+
+func (v *lazyValue) DiffIgnore() bool {
+	return !v.Loaded
+}
+
+func TestDiffIgnored(t *testing.T) {
+	type service struct {
+		Name  string         `diff:"name"`
+		Cache transientCache `diff:"cache"`
+		Lazy  *lazyValue     `diff:"lazy"`
+	}
+
+	cases := []struct {
+		Name  string
+		A, B  interface{}
+		Paths [][]string
+	}{
+		{
+			"transient-cache-ignored",
+			service{Name: "a", Cache: transientCache{Entries: map[string]string{"x": "1"}, Transient: true}},
+			service{Name: "b", Cache: transientCache{Entries: map[string]string{"x": "2"}, Transient: true}},
+			[][]string{{"name"}},
+		},
+		{
+			"persistent-cache-diffed",
+			service{Cache: transientCache{Entries: map[string]string{"x": "1"}}},
+			service{Cache: transientCache{Entries: map[string]string{"x": "2"}}},
+			[][]string{{"cache", "Entries", "x"}},
+		},
+		{
+			"one-side-transient",
+			service{Cache: transientCache{Entries: map[string]string{"x": "1"}}},
+			service{Cache: transientCache{Entries: map[string]string{"x": "2"}, Transient: true}},
+			[][]string{},
+		},
+		{
+			"pointer-not-loaded",
+			service{Lazy: &lazyValue{Value: "a"}},
+			service{Lazy: &lazyValue{Value: "b"}},
+			[][]string{},
+		},
+		{
+			"pointer-loaded",
+			service{Lazy: &lazyValue{Value: "a", Loaded: true}},
+			service{Lazy: &lazyValue{Value: "b", Loaded: true}},
+			[][]string{{"lazy", "Value"}},
+		},
+		{
+			"pointer-nil",
+			service{},
+			service{Lazy: &lazyValue{Value: "b", Loaded: true}},
+			[][]string{{"lazy"}},
+		},
+		{
+			"struct-values",
+			nil,
+			&service{Name: "a", Cache: transientCache{Transient: true}},
+			[][]string{{"name"}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B)
+			require.Nil(t, err)
+			require.Equal(t, len(tc.Paths), len(cl))
+
+			for i, c := range cl {
+				assert.Equal(t, tc.Paths[i], c.Path)
+			}
+		})
+	}
+}
+
+func TestDiffUntaggedStructsAtomic(t *testing.T) {
+	type vendor struct {
+		Host string
+		Port int
+	}
+	type config struct {
+		Name   string  `diff:"name"`
+		Vendor vendor  `diff:"vendor"`
+		Backup *vendor `diff:"backup"`
+	}
+
+	cases := []struct {
+		Name      string
+		A, B      interface{}
+		Changelog diff.Changelog
+	}{
+		{
+			"untagged-field",
+			config{Name: "a", Vendor: vendor{"localhost", 80}},
+			config{Name: "a", Vendor: vendor{"example.com", 443}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"vendor"}, From: vendor{"localhost", 80}, To: vendor{"example.com", 443}},
+			},
+		},
+		{
+			"untagged-unchanged",
+			config{Vendor: vendor{"localhost", 80}},
+			config{Vendor: vendor{"localhost", 80}},
+			diff.Changelog{},
+		},
+		{
+			"untagged-pointer-created",
+			config{Backup: nil},
+			config{Backup: &vendor{"localhost", 80}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"backup"}, From: nil, To: &vendor{"localhost", 80}},
+			},
+		},
+		{
+			"untagged-pointer-changed",
+			config{Backup: &vendor{"localhost", 80}},
+			config{Backup: &vendor{"localhost", 81}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"backup"}, From: vendor{"localhost", 80}, To: vendor{"localhost", 81}},
+			},
+		},
+		{
+			"tagged-struct-descends",
+			config{Name: "a"},
+			config{Name: "b"},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"name"}, From: "a", To: "b"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B, diff.UntaggedStructsAtomic(true))
+			require.Nil(t, err)
+			require.Equal(t, len(tc.Changelog), len(cl))
+
+			for i, c := range cl {
+				assert.Equal(t, tc.Changelog[i].Type, c.Type)
+				assert.Equal(t, tc.Changelog[i].Path, c.Path)
+				assert.Equal(t, tc.Changelog[i].From, c.From)
+				assert.Equal(t, tc.Changelog[i].To, c.To)
+			}
+		})
+	}
+
+	cl, err := diff.Diff(config{Vendor: vendor{"a", 1}}, config{Vendor: vendor{"b", 2}})
+	require.Nil(t, err)
+	assert.Len(t, cl, 2)
+}
+
+func TestDiffCompareFuncByPointer(t *testing.T) {
+	type handlers struct {
+		Name     string       `diff:"name"`
+		OnChange func() error `diff:"on_change"`
+	}
+
+	first := func() error { return nil }
+	second := func() error { return context.Canceled }
+
+	cases := []struct {
+		Name  string
+		A, B  handlers
+		Paths [][]string
+	}{
+		{"nil-to-func", handlers{}, handlers{OnChange: first}, [][]string{{"on_change"}}},
+		{"func-to-nil", handlers{OnChange: first}, handlers{}, [][]string{{"on_change"}}},
+		{"func-to-different-func", handlers{OnChange: first}, handlers{OnChange: second}, [][]string{{"on_change"}}},
+		{"same-func", handlers{OnChange: first}, handlers{OnChange: first}, [][]string{}},
+		{"both-nil", handlers{Name: "a"}, handlers{Name: "b"}, [][]string{{"name"}}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			d, err := diff.NewDiffer(diff.CompareFuncByPointer(true))
+			require.Nil(t, err)
+
+			cl, err := d.Diff(tc.A, tc.B)
+			require.Nil(t, err)
+			require.Equal(t, len(tc.Paths), len(cl))
+
+			for i, c := range cl {
+				assert.Equal(t, diff.UPDATE, c.Type)
+				assert.Equal(t, tc.Paths[i], c.Path)
+			}
+
+			// patching functions is a no-op
+			target := tc.A
+			pl := d.Patch(cl, &target)
+			assert.False(t, pl.HasErrors())
+			assert.Equal(t, tc.A.OnChange == nil, target.OnChange == nil)
+		})
+	}
+
+	_, err := diff.Diff(handlers{}, handlers{OnChange: first})
+	assert.Error(t, err)
+}
+
+func TestDiffMapValueEqual(t *testing.T) {
+	// values are equal when their foo matches, regardless of bar
+	equal := func(a, b interface{}) bool {
+		return a.(tmstruct).Foo == b.(tmstruct).Foo
+	}
+
+	cases := []struct {
+		Name      string
+		A, B      interface{}
+		Changelog diff.Changelog
+	}{
+		{
+			"equal-by-predicate",
+			map[string]tmstruct{"a": {"one", 1}},
+			map[string]tmstruct{"a": {"one", 2}},
+			diff.Changelog{},
+		},
+		{
+			"different-by-predicate",
+			map[string]tmstruct{"a": {"one", 1}},
+			map[string]tmstruct{"a": {"two", 1}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"a"}, From: tmstruct{"one", 1}, To: tmstruct{"two", 1}},
+			},
+		},
+		{
+			"created",
+			map[string]tmstruct{},
+			map[string]tmstruct{"a": {"one", 1}},
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"a", "foo"}, To: "one"},
+				diff.Change{Type: diff.CREATE, Path: []string{"a", "bar"}, To: 1},
+			},
+		},
+		{
+			"deleted",
+			map[string]tmstruct{"a": {"one", 1}},
+			map[string]tmstruct{},
+			diff.Changelog{
+				diff.Change{Type: diff.DELETE, Path: []string{"a", "foo"}, From: "one"},
+				diff.Change{Type: diff.DELETE, Path: []string{"a", "bar"}, From: 1},
+			},
+		},
+		{
+			"slices-unaffected",
+			[]tmstruct{{"one", 1}},
+			[]tmstruct{{"one", 2}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"0", "bar"}, From: 1, To: 2},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B, diff.MapValueEqual(equal))
+			require.Nil(t, err)
+			require.Equal(t, len(tc.Changelog), len(cl))
+
+			for i, c := range cl {
+				assert.Equal(t, tc.Changelog[i].Type, c.Type)
+				assert.Equal(t, tc.Changelog[i].Path, c.Path)
+				assert.Equal(t, tc.Changelog[i].From, c.From)
+				assert.Equal(t, tc.Changelog[i].To, c.To)
+			}
+		})
+	}
+}
+
+func TestDiffMapOrdering(t *testing.T) {
+	a := map[string]int{}
+	b := map[string]int{}
+	for i := 0; i < 10; i++ {
+		k := strconv.Itoa(i * 7 % 10)
+		a[k] = i
+		b[k] = i + 1
+	}
+
+	cl1, err := diff.Diff(a, b)
+	require.Nil(t, err)
+	require.Len(t, cl1, 10)
+
+	cl2, err := diff.Diff(a, b)
+	require.Nil(t, err)
+	assert.Equal(t, cl1, cl2)
+
+	for i, c := range cl1 {
+		assert.Equal(t, []string{strconv.Itoa(i)}, c.Path)
+	}
+
+	cl1, err = diff.Diff(nil, a)
+	require.Nil(t, err)
+	require.Len(t, cl1, 10)
+
+	for i, c := range cl1 {
+		assert.Equal(t, []string{strconv.Itoa(i)}, c.Path)
+	}
+}
+
+func TestDiffSliceIndexOrdering(t *testing.T) {
+	a := make([]int, 12)
+	for i := range a {
+		a[i] = i
+	}
+
+	// slice changes are emitted in index order, not in the order of their path segments
+	cl, err := diff.Diff(a, []int{0, 1})
+	require.Nil(t, err)
+	require.Len(t, cl, 10)
+	for i, c := range cl {
+		assert.Equal(t, []string{strconv.Itoa(i + 2)}, c.Path)
+	}
+
+	b := append([]int{0, 1, 20}, a[3:]...)
+	b = append(b, 12, 13)
+
+	cl, err = diff.Diff(a, b, diff.SliceOrdering(true))
+	require.Nil(t, err)
+	require.Len(t, cl, 3)
+	assert.Equal(t, []string{"2"}, cl[0].Path)
+	assert.Equal(t, []string{"12"}, cl[1].Path)
+	assert.Equal(t, []string{"13"}, cl[2].Path)
+}
+
+func TestDiffComparativeOrdering(t *testing.T) {
+	a := []tistruct{{"c", 1}, {"a", 2}, {"e", 3}, {"b", 4}}
+	b := []tistruct{{"d", 5}, {"b", 6}, {"f", 7}, {"c", 1}}
+
+	cl1, err := diff.Diff(a, b)
+	require.Nil(t, err)
+
+	for i := 0; i < 10; i++ {
+		cl2, err := diff.Diff(a, b)
+		require.Nil(t, err)
+		assert.Equal(t, cl1, cl2)
+	}
+
+	var paths []string
+	for _, c := range cl1 {
+		paths = append(paths, c.Type+":"+diff.Path(c.Path).String())
+	}
+
+	assert.Equal(t, []string{
+		"delete:a.name", "delete:a.value",
+		"update:b.value",
+		"create:d.name", "create:d.value",
+		"delete:e.name", "delete:e.value",
+		"create:f.name", "create:f.value",
+	}, paths)
+}
+
+func TestDiffOmitEmpty(t *testing.T) {
+	type spec struct {
+		Name     string            `json:"name"`
+		Replicas int               `json:"replicas,omitempty"`
+		Paused   bool              `json:"paused,omitempty"`
+		Labels   map[string]string `json:"labels,omitempty"`
+		Args     []string          `json:"args,omitempty"`
+		Extra    interface{}       `json:"extra,omitempty"`
+	}
+
+	// the same document, once with zero values and once with them omitted
+	type full struct {
+		Name     string            `json:"name"`
+		Replicas int               `json:"replicas"`
+		Paused   bool              `json:"paused"`
+		Labels   map[string]string `json:"labels"`
+		Args     []string          `json:"args"`
+	}
+
+	var a, b map[string]interface{}
+
+	data, err := json.Marshal(full{Name: "web", Labels: map[string]string{}, Args: []string{}})
+	require.Nil(t, err)
+	require.Nil(t, json.Unmarshal(data, &a))
+
+	data, err = json.Marshal(spec{Name: "api"})
+	require.Nil(t, err)
+	require.Nil(t, json.Unmarshal(data, &b))
+
+	cl, err := diff.Diff(a, b)
+	require.Nil(t, err)
+	assert.Len(t, cl, 5)
+
+	cl, err = diff.Diff(a, b, diff.OmitEmpty(true))
+	require.Nil(t, err)
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.UPDATE, Path: []string{"name"}, From: "web", To: "api"},
+	}, cl)
+
+	// decoding the omitted document back into the struct. The empty slice held by the interface
+	// field is reported as deleted, unless the field is tagged with omitempty
+	var c spec
+	require.Nil(t, json.Unmarshal(data, &c))
+
+	cl, err = diff.Diff(spec{Name: "api", Labels: map[string]string{}, Extra: []interface{}{}}, c, diff.TagName("json"))
+	require.Nil(t, err)
+	require.Len(t, cl, 1)
+	assert.Equal(t, []string{"extra"}, cl[0].Path)
+
+	cl, err = diff.Diff(spec{Name: "api", Labels: map[string]string{}, Extra: []interface{}{}}, c, diff.TagName("json"), diff.OmitEmpty(true))
+	require.Nil(t, err)
+	assert.Len(t, cl, 0)
+
+	type untagged struct {
+		Extra interface{} `json:"extra"`
+	}
+
+	cl, err = diff.Diff(untagged{Extra: []interface{}{}}, untagged{}, diff.TagName("json"), diff.OmitEmpty(true))
+	require.Nil(t, err)
+	assert.Len(t, cl, 1)
+
+	// non-empty values are still reported as deleted
+	cl, err = diff.Diff(map[string]interface{}{"a": 1, "b": 0}, map[string]interface{}{}, diff.OmitEmpty(true))
+	require.Nil(t, err)
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.DELETE, Path: []string{"a"}, From: 1},
+	}, cl)
+}
+
+func TestDiffIgnorePaths(t *testing.T) {
+	type metadata struct {
+		Name      string    `diff:"name"`
+		CreatedAt time.Time `diff:"createdAt"`
+		UpdatedAt time.Time `diff:"updatedAt"`
+	}
+
+	type resource struct {
+		Metadata metadata          `diff:"metadata"`
+		Status   map[string]string `diff:"status"`
+		Spec     []string          `diff:"spec"`
+	}
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	a := resource{
+		Metadata: metadata{Name: "a", CreatedAt: now, UpdatedAt: now},
+		Status:   map[string]string{"phase": "pending", "reason": "init"},
+		Spec:     []string{"x"},
+	}
+
+	b := resource{
+		Metadata: metadata{Name: "b", CreatedAt: now.Add(time.Hour), UpdatedAt: now.Add(time.Hour)},
+		Status:   map[string]string{"phase": "running", "message": "ok"},
+		Spec:     []string{"y"},
+	}
+
+	cases := []struct {
+		Name      string
+		Paths     [][]string
+		Changelog diff.Changelog
+	}{
+		{
+			"nested-timestamp", [][]string{{"metadata", "updatedAt"}, {"status"}, {"spec"}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"metadata", "name"}, From: "a", To: "b"},
+				diff.Change{Type: diff.UPDATE, Path: []string{"metadata", "createdAt"}, From: now, To: now.Add(time.Hour)},
+			},
+		},
+		{
+			"regex", [][]string{{"metadata", ".*At"}, {"status", "^(phase|message)$"}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"metadata", "name"}, From: "a", To: "b"},
+				diff.Change{Type: diff.DELETE, Path: []string{"status", "reason"}, From: "init"},
+				diff.Change{Type: diff.UPDATE, Path: []string{"spec", "0"}, From: "x", To: "y"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(a, b, diff.IgnorePaths(tc.Paths...), diff.DiscardComplexOrigin())
+			require.Nil(t, err)
+			assert.Equal(t, tc.Changelog, cl)
+		})
+	}
+}
+
+func TestDiffAtomicTypes(t *testing.T) {
+	type config struct {
+		Endpoints []string       `diff:"endpoints"`
+		Limits    map[string]int `diff:"limits"`
+	}
+
+	type service struct {
+		Name    string   `diff:"name"`
+		Config  config   `diff:"config"`
+		Backup  *config  `diff:"backup"`
+		Tags    []string `diff:"tags"`
+		Configs []config `diff:"configs"`
+	}
+
+	a := service{
+		Name:    "api",
+		Config:  config{Endpoints: []string{"a"}, Limits: map[string]int{"cpu": 1}},
+		Tags:    []string{"x"},
+		Configs: []config{{Endpoints: []string{"a"}}},
+	}
+
+	b := service{
+		Name:    "api",
+		Config:  config{Endpoints: []string{"a", "b"}, Limits: map[string]int{"cpu": 2}},
+		Backup:  &config{Endpoints: []string{"c"}},
+		Tags:    []string{"y"},
+		Configs: []config{{Endpoints: []string{"a"}}},
+	}
+
+	cl, err := diff.Diff(a, b, diff.AtomicTypes(reflect.TypeOf(config{})), diff.DiscardComplexOrigin())
+	require.Nil(t, err)
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.UPDATE, Path: []string{"config"}, From: a.Config, To: b.Config},
+		diff.Change{Type: diff.UPDATE, Path: []string{"backup"}, To: b.Backup},
+		diff.Change{Type: diff.UPDATE, Path: []string{"tags", "0"}, From: "x", To: "y"},
+	}, cl)
+
+	cl, err = diff.Diff(a, b)
+	require.Nil(t, err)
+	assert.Len(t, cl, 4)
+}
+
+func TestDiffTrackComparativeIndices(t *testing.T) {
+	a := []tistruct{{"one", 1}, {"two", 2}, {"three", 3}}
+	b := []tistruct{{"three", 3}, {"four", 4}, {"one", 10}}
+
+	idx := func(i int) *int { return &i }
+
+	cl, err := diff.Diff(a, b, diff.TrackComparativeIndices(true), diff.DiscardComplexOrigin())
+	require.Nil(t, err)
+
+	expected := diff.Changelog{
+		diff.Change{Type: diff.CREATE, Path: []string{"four", "name"}, To: "four", ToIndex: idx(1)},
+		diff.Change{Type: diff.CREATE, Path: []string{"four", "value"}, To: 4, ToIndex: idx(1)},
+		diff.Change{Type: diff.UPDATE, Path: []string{"one", "value"}, From: 1, To: 10, FromIndex: idx(0), ToIndex: idx(2)},
+		diff.Change{Type: diff.DELETE, Path: []string{"two", "name"}, From: "two", FromIndex: idx(1)},
+		diff.Change{Type: diff.DELETE, Path: []string{"two", "value"}, From: 2, FromIndex: idx(1)},
+	}
+
+	// the changes also record the element they belong to, which isn't exported
+	require.Len(t, cl, len(expected))
+	for i, c := range expected {
+		assert.Equal(t, c.Type, cl[i].Type)
+		assert.Equal(t, c.Path, cl[i].Path)
+		assert.Equal(t, c.From, cl[i].From)
+		assert.Equal(t, c.To, cl[i].To)
+		assert.Equal(t, c.FromIndex, cl[i].FromIndex)
+		assert.Equal(t, c.ToIndex, cl[i].ToIndex)
+	}
+
+	cl, err = diff.Diff(a, b)
+	require.Nil(t, err)
+	for _, c := range cl {
+		assert.Nil(t, c.FromIndex)
+		assert.Nil(t, c.ToIndex)
+	}
+
+	data, err := json.Marshal(diff.Change{Type: diff.UPDATE, Path: []string{"one", "value"}, From: 1, To: 10, FromIndex: idx(0), ToIndex: idx(2)})
+	require.Nil(t, err)
+
+	var c diff.Change
+	require.Nil(t, json.Unmarshal(data, &c))
+	assert.Equal(t, idx(0), c.FromIndex)
+	assert.Equal(t, idx(2), c.ToIndex)
+}
+
+func TestDiffRedact(t *testing.T) {
+	type credentials struct {
+		User     string   `diff:"user"`
+		Password string   `diff:"password,redact"`
+		Keys     []string `diff:"keys,redact"`
+		Token    *string  `diff:"token,redact"`
+	}
+
+	type account struct {
+		Name  string      `diff:"name"`
+		Creds credentials `diff:"creds"`
+	}
+
+	token := "secret-token"
+
+	cases := []struct {
+		Name      string
+		A, B      interface{}
+		Changelog diff.Changelog
+	}{
+		{
+			"update",
+			credentials{User: "admin", Password: "hunter2", Keys: []string{"k1"}},
+			credentials{User: "root", Password: "hunter3", Keys: []string{"k2", "k3"}, Token: &token},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"user"}, From: "admin", To: "root"},
+				diff.Change{Type: diff.UPDATE, Path: []string{"password"}, From: diff.RedactedValue, To: diff.RedactedValue},
+				diff.Change{Type: diff.UPDATE, Path: []string{"keys", "0"}, From: diff.RedactedValue, To: diff.RedactedValue},
+				diff.Change{Type: diff.CREATE, Path: []string{"keys", "1"}, To: diff.RedactedValue},
+				diff.Change{Type: diff.UPDATE, Path: []string{"token"}, To: diff.RedactedValue},
+			},
+		},
+		{
+			"unchanged",
+			credentials{User: "admin", Password: "hunter2"},
+			credentials{User: "admin", Password: "hunter2"},
+			nil,
+		},
+		{
+			"create",
+			nil,
+			&credentials{User: "admin", Password: "hunter2"},
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"user"}, To: "admin"},
+				diff.Change{Type: diff.CREATE, Path: []string{"password"}, To: diff.RedactedValue},
+			},
+		},
+		{
+			"nested",
+			account{Name: "a", Creds: credentials{Password: "hunter2"}},
+			account{Name: "b", Creds: credentials{Password: "hunter2"}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"name"}, From: "a", To: "b"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B)
+			require.Nil(t, err)
+			require.Len(t, cl, len(tc.Changelog))
+
+			for i, c := range cl {
+				assert.Equal(t, tc.Changelog[i].Type, c.Type)
+				assert.Equal(t, tc.Changelog[i].Path, c.Path)
+				assert.Equal(t, tc.Changelog[i].From, c.From)
+				assert.Equal(t, tc.Changelog[i].To, c.To)
+				assert.NotContains(t, fmt.Sprintf("%#v", c), "hunter")
+			}
+		})
+	}
+
+	// redacted changes no longer hold their values, so they are ignored when patching
+	a := credentials{User: "admin", Password: "hunter2", Keys: []string{"k1"}}
+	b := credentials{User: "root", Password: "hunter3", Keys: []string{"k2"}}
+
+	cl, err := diff.Diff(a, b)
+	require.Nil(t, err)
+
+	pl := diff.Patch(cl, &a)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, credentials{User: "root", Password: "hunter2", Keys: []string{"k1"}}, a)
+	for _, ple := range pl[1:] {
+		assert.True(t, ple.HasFlag(diff.FlagIgnored))
+	}
+
+	pl = diff.Unpatch(cl, &a)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, credentials{User: "admin", Password: "hunter2", Keys: []string{"k1"}}, a)
+
+	pl = diff.CompilePatch(cl, credentials{}).Patch(&a)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, "hunter2", a.Password)
+
+	// redacted changes are still ignored once the changelog has been serialized
+	data, err := json.Marshal(cl)
+	require.Nil(t, err)
+
+	var jcl diff.Changelog
+	require.Nil(t, json.Unmarshal(data, &jcl))
+
+	pl = diff.Patch(jcl, &a)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, credentials{User: "root", Password: "hunter2", Keys: []string{"k1"}}, a)
+
+	data, err = cl.MarshalMsgpack()
+	require.Nil(t, err)
+
+	mcl, err := diff.UnmarshalChangelog(data)
+	require.Nil(t, err)
+
+	a.User = "admin"
+	pl = diff.Patch(mcl, &a)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, credentials{User: "root", Password: "hunter2", Keys: []string{"k1"}}, a)
+}
+
+func TestDiffStringSynonyms(t *testing.T) {
+	groups := [][]string{{"yes", "true", "1", "on"}, {"no", "false", "0", "off"}}
+
+	cases := []struct {
+		Name      string
+		A, B      interface{}
+		Changelog diff.Changelog
+	}{
+		{"same-group", "yes", "true", diff.Changelog{}},
+		{"different-group", "yes", "no", diff.Changelog{
+			diff.Change{Type: diff.UPDATE, Path: []string{}, From: "yes", To: "no"},
+		}},
+		{"not-in-group", "yes", "maybe", diff.Changelog{
+			diff.Change{Type: diff.UPDATE, Path: []string{}, From: "yes", To: "maybe"},
+		}},
+		{"ungrouped", "enabled", "active", diff.Changelog{
+			diff.Change{Type: diff.UPDATE, Path: []string{}, From: "enabled", To: "active"},
+		}},
+		{"map", map[string]string{"debug": "1", "verbose": "off"}, map[string]string{"debug": "on", "verbose": "1"}, diff.Changelog{
+			diff.Change{Type: diff.UPDATE, Path: []string{"verbose"}, From: "off", To: "1"},
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B, diff.StringSynonyms(groups))
+			require.Nil(t, err)
+			assert.Equal(t, tc.Changelog, cl)
+		})
+	}
+}
+
+func TestDiffChunkedSlices(t *testing.T) {
+	const size = 4 << 20
+
+	a := make([]byte, size)
+	for i := range a {
+		a[i] = byte(i)
+	}
+
+	b := make([]byte, size)
+	copy(b, a)
+
+	// a single byte, a run spanning two blocks, and the final byte
+	b[10] = 0xff
+	copy(b[8190:8200], bytes.Repeat([]byte{0xaa}, 10))
+	b[size-1]++
+
+	cl, err := diff.Diff(a, b, diff.ChunkedSlices(4096))
+	require.Nil(t, err)
+	require.Len(t, cl, 3)
+
+	assert.Equal(t, diff.Change{Type: diff.UPDATE, Path: []string{"10"}, From: []byte{10}, To: []byte{0xff}}, cl[0])
+	assert.Equal(t, diff.UPDATE, cl[1].Type)
+	assert.Equal(t, []string{"8190"}, cl[1].Path)
+	assert.Equal(t, a[8190:8200], cl[1].From)
+	assert.Equal(t, b[8190:8200], cl[1].To)
+	assert.Equal(t, []string{strconv.Itoa(size - 1)}, cl[2].Path)
+
+	// changes hold copies of the differing elements
+	b[10] = 0
+	assert.Equal(t, []byte{0xff}, cl[0].To)
+
+	cl, err = diff.Diff(a, a[:size-100], diff.ChunkedSlices(4096))
+	require.Nil(t, err)
+	require.Len(t, cl, 1)
+	assert.Equal(t, diff.DELETE, cl[0].Type)
+	assert.Equal(t, []string{strconv.Itoa(size - 100)}, cl[0].Path)
+	assert.Len(t, cl[0].From, 100)
+
+	x := make([]int64, 1<<20)
+	y := make([]int64, len(x)+2)
+	y[500000], y[500001] = 1, 2
+
+	cl, err = diff.Diff(x, y, diff.ChunkedSlices(1024))
+	require.Nil(t, err)
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.UPDATE, Path: []string{"500000"}, From: []int64{0, 0}, To: []int64{1, 2}},
+		diff.Change{Type: diff.CREATE, Path: []string{strconv.Itoa(len(x))}, To: []int64{0, 0}},
+	}, cl)
+
+	cl, err = diff.Diff(x, x, diff.ChunkedSlices(1024))
+	require.Nil(t, err)
+	assert.Empty(t, cl)
+
+	_, err = diff.NewDiffer(diff.ChunkedSlices(0))
+	assert.Error(t, err)
+}
+
+func TestDiffMaxValueBytes(t *testing.T) {
+	type document struct {
+		Title string   `diff:"title"`
+		Body  string   `diff:"body"`
+		Lines []string `diff:"lines"`
+	}
+
+	large := strings.Repeat("x", 1000)
+	lines := make([]string, 200)
+	for i := range lines {
+		lines[i] = strconv.Itoa(i)
+	}
+
+	cases := []struct {
+		Name      string
+		A, B      interface{}
+		Changelog diff.Changelog
+	}{
+		{
+			"large-string",
+			document{Title: "a", Body: "short"}, document{Title: "b", Body: large},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"title"}, From: "a", To: "b"},
+				diff.Change{Type: diff.UPDATE, Path: []string{"body"}, From: "short", To: diff.TruncatedValue{Size: 1003}},
+			},
+		},
+		{
+			"large-nested-slice",
+			map[string]interface{}{},
+			map[string]interface{}{"doc": map[string]interface{}{"lines": lines}},
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"doc"}, To: diff.TruncatedValue{Size: 700}},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B, diff.MaxValueBytes(256), diff.DiscardComplexOrigin())
+			require.Nil(t, err)
+			assert.Equal(t, tc.Changelog, cl)
+
+			// the truncated changelog still round trips through json, when its types are recorded
+			cl, err = diff.Diff(tc.A, tc.B, diff.MaxValueBytes(256), diff.DiscardComplexOrigin(), diff.TypedValues(true))
+			require.Nil(t, err)
+
+			data, err := json.Marshal(cl)
+			require.Nil(t, err)
+
+			var rcl diff.Changelog
+			require.Nil(t, json.Unmarshal(data, &rcl))
+			assert.Equal(t, cl[len(cl)-1].To, rcl[len(rcl)-1].To)
+		})
+	}
+
+	// truncated values are ignored when patching, as the actual values are unknown
+	doc := document{Title: "a", Body: "short"}
+
+	cl, err := diff.Diff(doc, document{Title: "b", Body: large}, diff.MaxValueBytes(256))
+	require.Nil(t, err)
+
+	pl := diff.Patch(cl, &doc)
+	assert.False(t, pl.HasErrors())
+	assert.True(t, pl[1].HasFlag(diff.FlagIgnored))
+	assert.Equal(t, document{Title: "b", Body: "short"}, doc)
+
+	m := map[string]interface{}{}
+
+	cl, err = diff.Diff(m, map[string]interface{}{"doc": map[string]interface{}{"lines": lines}}, diff.MaxValueBytes(256))
+	require.Nil(t, err)
+
+	pl = diff.Patch(cl, &m)
+	assert.False(t, pl.HasErrors())
+	assert.Empty(t, m)
+}
+
+func TestDiffWarnOnIdentifierChange(t *testing.T) {
+	a := []tistruct{{"one", 1}, {"two", 2}, {"three", 3}}
+	b := []tistruct{{"one", 1}, {"zwei", 2}, {"four", 4}, {"three", 3}}
+
+	cl, err := diff.Diff(a, b, diff.WarnOnIdentifierChange(true))
+	require.Nil(t, err)
+
+	warnings := cl.FilterFunc(func(c diff.Change) bool { return c.Type == diff.WARNING })
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.WARNING, Path: []string{"two"}, From: "two", To: "zwei"},
+	}, warnings)
+
+	// the element is still reported as deleted and created
+	assert.Len(t, cl, 7)
+
+	// warnings are not applied
+	target := []tistruct{{"one", 1}, {"two", 2}, {"three", 3}}
+	pl := diff.Patch(warnings, &target)
+	assert.False(t, pl.HasErrors())
+	assert.True(t, pl[0].HasFlag(diff.FlagIgnored))
+	assert.Equal(t, a, target)
+
+	cl, err = diff.Diff(a, b)
+	require.Nil(t, err)
+	assert.Len(t, cl, 6)
+}
+
+func TestDiffTimeMonotonic(t *testing.T) {
+	now := time.Now()
+
+	parsed, err := time.Parse(time.RFC3339Nano, now.Format(time.RFC3339Nano))
+	require.Nil(t, err)
+
+	cl, err := diff.Diff(now, parsed)
+	require.Nil(t, err)
+	assert.Empty(t, cl)
+
+	later := now.Add(time.Second)
+
+	cl, err = diff.Diff(now, later)
+	require.Nil(t, err)
+	require.Len(t, cl, 1)
+
+	// stored values carry no monotonic reading, so they equal the same instant deserialized
+	from := cl[0].From.(time.Time)
+	to := cl[0].To.(time.Time)
+	assert.Equal(t, now.Round(0), from)
+	assert.Equal(t, later.Round(0), to)
+	assert.Equal(t, from.String(), parsed.String())
+
+	cases := []struct {
+		Name    string
+		A, B    time.Time
+		Changed bool
+	}{
+		{"zero-zero", time.Time{}, time.Time{}, false},
+		{"zero-value", time.Time{}, now, true},
+		{"value-zero", now, time.Time{}, true},
+		{"zero-unix-epoch", time.Time{}, time.Unix(0, 0), true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B)
+			require.Nil(t, err)
+			assert.Equal(t, tc.Changed, len(cl) == 1)
+		})
+	}
+}
+
+func TestDiffSemanticJSON(t *testing.T) {
+	type event struct {
+		Name    string          `diff:"name"`
+		Payload json.RawMessage `diff:"payload"`
+		Extra   []byte          `diff:"extra,json"`
+		Raw     []byte          `diff:"raw"`
+	}
+
+	cases := []struct {
+		Name      string
+		A, B      event
+		Changelog diff.Changelog
+	}{
+		{
+			"reordered-keys",
+			event{Payload: json.RawMessage(`{"a": 1, "b": [1, 2]}`), Extra: []byte(`{"x":true,"y":null}`)},
+			event{Payload: json.RawMessage(`{"b":[1,2],"a":1}`), Extra: []byte("{\n  \"y\": null,\n  \"x\": true\n}")},
+			diff.Changelog{},
+		},
+		{
+			"changed-value",
+			event{Payload: json.RawMessage(`{"a": 1, "b": {"c": "d"}}`)},
+			event{Payload: json.RawMessage(`{"b": {"c": "e"}, "a": 1}`)},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"payload", "b", "c"}, From: "d", To: "e"},
+			},
+		},
+		{
+			"changed-type",
+			event{Extra: []byte(`{"a": "1"}`)},
+			event{Extra: []byte(`{"a": 1}`)},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"extra", "a"}, From: "1", To: float64(1)},
+			},
+		},
+		{
+			"untagged-bytes",
+			event{Raw: []byte(`{"a":1}`)},
+			event{Raw: []byte(`{"a": 1}`)},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"raw"}, From: []byte(`{"a":1}`), To: []byte(`{"a": 1}`)},
+			},
+		},
+		{
+			"invalid-json",
+			event{Payload: json.RawMessage(`{"a":1}`)},
+			event{Payload: json.RawMessage(`{"a":`)},
+			diff.Changelog{
+				diff.Change{Type: diff.WARNING, Path: []string{"payload"}, To: "unexpected end of JSON input"},
+				diff.Change{Type: diff.UPDATE, Path: []string{"payload"}, From: json.RawMessage(`{"a":1}`), To: json.RawMessage(`{"a":`)},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B, diff.SemanticJSON(true), diff.DiscardComplexOrigin())
+			require.Nil(t, err)
+			assert.Equal(t, tc.Changelog, cl)
+		})
+	}
+}
+
+func TestDiffMixedInterfaceSlice(t *testing.T) {
+	cases := []struct {
+		Name      string
+		A, B      []interface{}
+		Ordered   bool
+		Changelog diff.Changelog
+	}{
+		{
+			"set-reordered", []interface{}{1, "a", true, 2.5, nil}, []interface{}{nil, true, 2.5, "a", 1}, false,
+			diff.Changelog{},
+		},
+		{
+			"set-changed", []interface{}{1, "a", true}, []interface{}{true, "b", 1}, false,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"1"}, From: "a", To: "b"},
+			},
+		},
+		{
+			"set-boxed-numbers", []interface{}{1, "a"}, []interface{}{"a", int64(1)}, false,
+			diff.Changelog{
+				diff.Change{Type: diff.DELETE, Path: []string{"0"}, From: 1},
+				diff.Change{Type: diff.CREATE, Path: []string{"1"}, To: int64(1)},
+			},
+		},
+		{
+			"ordered-reordered", []interface{}{1, "a", true}, []interface{}{"a", true, 1}, true,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"0"}, From: 1, To: "a"},
+				diff.Change{Type: diff.UPDATE, Path: []string{"1"}, From: "a", To: true},
+				diff.Change{Type: diff.UPDATE, Path: []string{"2"}, From: true, To: 1},
+			},
+		},
+		{
+			"ordered-boxed-numbers", []interface{}{1, "a"}, []interface{}{int64(1), "a"}, true,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"0"}, From: 1, To: int64(1)},
+			},
+		},
+		{
+			"ordered-same-type", []interface{}{1, "a"}, []interface{}{2, "a"}, true,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"0"}, From: 1, To: 2},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B, diff.SliceOrdering(tc.Ordered))
+			require.Nil(t, err)
+			assert.Equal(t, tc.Changelog, cl)
+
+			// allowing type mismatches makes no difference to values held by interfaces
+			cl, err = diff.Diff(tc.A, tc.B, diff.SliceOrdering(tc.Ordered), diff.AllowTypeMismatch(true))
+			require.Nil(t, err)
+			assert.Equal(t, tc.Changelog, cl)
+		})
+	}
+
+	// boxed numbers of a different type differ in both modes, unless compared loosely
+	for _, allow := range []bool{false, true} {
+		cl, err := diff.Diff([]interface{}{1}, []interface{}{int64(1)}, diff.SliceOrdering(true), diff.AllowTypeMismatch(allow))
+		require.Nil(t, err)
+		assert.Equal(t, diff.Changelog{diff.Change{Type: diff.UPDATE, Path: []string{"0"}, From: 1, To: int64(1)}}, cl)
+
+		cl, err = diff.Diff([]interface{}{1}, []interface{}{int64(1)}, diff.SliceOrdering(true), diff.AllowTypeMismatch(allow), diff.NumericEquality(true))
+		require.Nil(t, err)
+		assert.Empty(t, cl)
+	}
+}
+
+func TestDiffMapValueKey(t *testing.T) {
+	type user struct {
+		Email string `diff:"email"`
+		Name  string `diff:"name"`
+	}
+
+	byEmail := func(v interface{}) interface{} {
+		return v.(user).Email
+	}
+
+	a := map[string]user{
+		"id-1": {"a@example.com", "Alice"},
+		"id-2": {"b@example.com", "Bob"},
+		"id-3": {"c@example.com", "Carol"},
+	}
+
+	b := map[string]user{
+		"id-9": {"a@example.com", "Alice"},
+		"id-2": {"b@example.com", "Robert"},
+		"id-7": {"d@example.com", "Dave"},
+	}
+
+	cl, err := diff.Diff(a, b, diff.MapValueKey(byEmail), diff.DiscardComplexOrigin())
+	require.Nil(t, err)
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.DELETE, Path: []string{"id-1"}, From: user{"a@example.com", "Alice"}},
+		diff.Change{Type: diff.DELETE, Path: []string{"id-3", "email"}, From: "c@example.com"},
+		diff.Change{Type: diff.DELETE, Path: []string{"id-3", "name"}, From: "Carol"},
+		diff.Change{Type: diff.CREATE, Path: []string{"id-9"}, To: user{"a@example.com", "Alice"}},
+		diff.Change{Type: diff.UPDATE, Path: []string{"id-2", "name"}, From: "Bob", To: "Robert"},
+		diff.Change{Type: diff.CREATE, Path: []string{"id-7", "email"}, To: "d@example.com"},
+		diff.Change{Type: diff.CREATE, Path: []string{"id-7", "name"}, To: "Dave"},
+	}, cl)
+
+	// the changelog applies to the original map and can be undone
+	c := map[string]user{}
+	for k, v := range a {
+		c[k] = v
+	}
+
+	pl := diff.Patch(cl, &c)
+	require.False(t, pl.HasErrors())
+	assert.Equal(t, b, c)
+
+	pl = diff.Unpatch(cl, &c)
+	require.False(t, pl.HasErrors())
+	assert.Equal(t, a, c)
+
+	// without the option, the renamed key is deleted and created
+	cl, err = diff.Diff(a, b)
+	require.Nil(t, err)
+	assert.Len(t, cl, 9)
+
+	// entries that swapped keys
+	x := map[string]user{"k1": {"a@example.com", "Alice"}, "k2": {"b@example.com", "Bob"}}
+	y := map[string]user{"k1": {"b@example.com", "Bob"}, "k2": {"a@example.com", "Alice"}}
+
+	cl, err = diff.Diff(x, y, diff.MapValueKey(byEmail))
+	require.Nil(t, err)
+
+	pl = diff.Patch(cl, &x)
+	require.False(t, pl.HasErrors())
+	assert.Equal(t, y, x)
+
+	pl = diff.Unpatch(cl, &x)
+	require.False(t, pl.HasErrors())
+	assert.Equal(t, map[string]user{"k1": {"a@example.com", "Alice"}, "k2": {"b@example.com", "Bob"}}, x)
+
+	// derived keys must be unique within a map
+	b["id-8"] = user{"d@example.com", "David"}
+	_, err = diff.Diff(a, b, diff.MapValueKey(byEmail))
+	assert.NotNil(t, err)
+}
+
+func TestDiffTagFallback(t *testing.T) {
+	type address struct {
+		Street string `json:"street"`
+		City   string `json:"city,omitempty"`
+	}
+
+	type person struct {
+		ID      int    `diff:"id" json:"identifier"`
+		Name    string `json:"full_name"`
+		Age     int    `diff:",omitempty" json:"age"`
+		Secret  string `json:"-"`
+		Note    string
+		Address address `json:"address"`
+	}
+
+	a := person{ID: 1, Name: "a", Age: 1, Secret: "x", Note: "n", Address: address{"s1", "c1"}}
+	b := person{ID: 2, Name: "b", Age: 2, Secret: "y", Note: "m", Address: address{"s2", "c2"}}
+
+	cl, err := diff.Diff(a, b, diff.TagFallback("json"), diff.DiscardComplexOrigin())
+	require.Nil(t, err)
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.UPDATE, Path: []string{"id"}, From: 1, To: 2},
+		diff.Change{Type: diff.UPDATE, Path: []string{"full_name"}, From: "a", To: "b"},
+		diff.Change{Type: diff.UPDATE, Path: []string{"age"}, From: 1, To: 2},
+		diff.Change{Type: diff.UPDATE, Path: []string{"Note"}, From: "n", To: "m"},
+		diff.Change{Type: diff.UPDATE, Path: []string{"address", "street"}, From: "s1", To: "s2"},
+		diff.Change{Type: diff.UPDATE, Path: []string{"address", "city"}, From: "c1", To: "c2"},
+	}, cl)
+
+	d, err := diff.NewDiffer(diff.TagFallback("json"))
+	require.Nil(t, err)
+
+	target := a
+	pl := d.Patch(cl, &target)
+	assert.False(t, pl.HasErrors())
+	b.Secret = a.Secret
+	assert.Equal(t, b, target)
+}
+
+func TestDiffStructWholeThreshold(t *testing.T) {
+	type inner struct {
+		A int `diff:"a"`
+		B int `diff:"b"`
+		C int `diff:"c"`
+		D int `diff:"d"`
+	}
+
+	type outer struct {
+		Name  string `diff:"name"`
+		Kind  string `diff:"kind"`
+		Inner inner  `diff:"inner"`
+	}
+
+	a := outer{Name: "one", Kind: "x", Inner: inner{A: 1, B: 2, C: 3, D: 4}}
+	b := outer{Name: "one", Kind: "x", Inner: inner{A: 10, B: 20, C: 3, D: 4}}
+
+	cases := []struct {
+		Name      string
+		Threshold float64
+		Changelog diff.Changelog
+	}{
+		{
+			"below", 0.49,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"inner"}, From: a.Inner, To: b.Inner},
+			},
+		},
+		{
+			"equal", 0.5,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"inner", "a"}, From: 1, To: 10},
+				diff.Change{Type: diff.UPDATE, Path: []string{"inner", "b"}, From: 2, To: 20},
+			},
+		},
+		{
+			"above", 0.51,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"inner", "a"}, From: 1, To: 10},
+				diff.Change{Type: diff.UPDATE, Path: []string{"inner", "b"}, From: 2, To: 20},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(a, b, diff.StructWholeThreshold(tc.Threshold), diff.DiscardComplexOrigin())
+			require.Nil(t, err)
+			assert.Equal(t, tc.Changelog, cl)
+
+			target := a
+			pl := diff.Patch(cl, &target)
+			assert.False(t, pl.HasErrors())
+			assert.Equal(t, b, target)
+		})
+	}
+
+	_, err := diff.NewDiffer(diff.StructWholeThreshold(1.5))
+	assert.NotNil(t, err)
+}
+
+func TestDiffNumericEquality(t *testing.T) {
+	cases := []struct {
+		Name      string
+		A, B      interface{}
+		Changelog diff.Changelog
+	}{
+		{
+			"int-float-equal", 3, 3.0,
+			diff.Changelog{},
+		},
+		{
+			"int-float-different", 3, 3.5,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{}, From: 3, To: 3.5},
+			},
+		},
+		{
+			"map-int-float-equal", map[string]interface{}{"n": 3}, map[string]interface{}{"n": 3.0},
+			diff.Changelog{},
+		},
+		{
+			"map-int-float-different", map[string]interface{}{"n": 3}, map[string]interface{}{"n": 3.5},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"n"}, From: 3, To: 3.5},
+			},
+		},
+		{
+			"uint-float-equal", uint8(200), float32(200),
+			diff.Changelog{},
+		},
+		{
+			"int-float-equal-at-2^53", int64(1 << 53), float64(1 << 53),
+			diff.Changelog{},
+		},
+		{
+			"int-float-beyond-2^53", int64(1<<53 + 1), float64(1 << 53),
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{}, From: int64(1<<53 + 1), To: float64(1 << 53)},
+			},
+		},
+		{
+			"int-uint-beyond-2^53", int64(1<<53 + 1), uint64(1 << 53),
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{}, From: int64(1<<53 + 1), To: uint64(1 << 53)},
+			},
+		},
+		{
+			"int-uint-equal-beyond-2^53", int64(1<<62 + 1), uint64(1<<62 + 1),
+			diff.Changelog{},
+		},
+		{
+			"negative-int-uint", int64(-1), uint64(1),
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{}, From: int64(-1), To: uint64(1)},
+			},
+		},
+		{
+			"min-int-float-equal", int64(math.MinInt64), float64(math.MinInt64),
+			diff.Changelog{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B, diff.NumericEquality(true), diff.AllowTypeMismatch(true))
+			require.Nil(t, err)
+			assert.Equal(t, tc.Changelog, cl)
+		})
+	}
+
+	cl, err := diff.Diff(3, 3.0, diff.AllowTypeMismatch(true))
+	require.Nil(t, err)
+	assert.Len(t, cl, 1)
+}
+
+type Namer interface {
+	GetName() string
+}
+
+type Tag struct {
+	ID   string `diff:"id,identifier"`
+	Name string `diff:"name"`
+}
+
+func (t *Tag) GetName() string {
+	return t.Name
+}
+
+func TestDiffIdentifiedInterfaceSlice(t *testing.T) {
+	var none Namer
+
+	cases := []struct {
+		Name      string
+		A, B      []Namer
+		Changelog diff.Changelog
+	}{
+		{
+			"reordered",
+			[]Namer{&Tag{"1", "a"}, &Tag{"2", "b"}},
+			[]Namer{&Tag{"2", "c"}, &Tag{"1", "a"}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"2", "name"}, From: "b", To: "c"},
+			},
+		},
+		{
+			"leading-nil",
+			[]Namer{none, &Tag{"1", "a"}, &Tag{"2", "a"}},
+			[]Namer{none, &Tag{"2", "a"}, &Tag{"1", "b"}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"1", "name"}, From: "a", To: "b"},
+			},
+		},
+		{
+			"created",
+			[]Namer{&Tag{"1", "a"}},
+			[]Namer{&Tag{"1", "a"}, &Tag{"2", "b"}},
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"2"}, To: &Tag{"2", "b"}},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B, diff.DiscardComplexOrigin())
+			require.Nil(t, err)
+			assert.Equal(t, tc.Changelog, cl)
+		})
+	}
+
+	a := map[string][]*Namer{"k": {nil}}
+	b := map[string][]*Namer{"k": {nil}}
+	n1, n2 := Namer(&Tag{"1", "a"}), Namer(&Tag{"1", "b"})
+	a["k"] = append(a["k"], &n1)
+	b["k"] = append(b["k"], &n2)
+
+	cl, err := diff.Diff(a, b, diff.DiscardComplexOrigin())
+	require.Nil(t, err)
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.UPDATE, Path: []string{"k", "1", "name"}, From: "a", To: "b"},
+	}, cl)
+}
+
+type hashedDoc struct {
+	Name string `diff:"name"`
+	Body string `diff:"body"`
+	hash uint64 `diff:"-"`
+}
+
+func (h hashedDoc) Hash() uint64 {
+	return h.hash
+}
+
+func TestDiffTrustHash(t *testing.T) {
+	type container struct {
+		Doc  hashedDoc   `diff:"doc"`
+		Docs []hashedDoc `diff:"docs"`
+	}
+
+	cases := []struct {
+		Name      string
+		A, B      container
+		Trust     bool
+		Changelog diff.Changelog
+	}{
+		{
+			"different-hash",
+			container{Doc: hashedDoc{Name: "a", hash: 1}},
+			container{Doc: hashedDoc{Name: "b", hash: 2}},
+			true,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"doc", "name"}, From: "a", To: "b"},
+			},
+		},
+		{
+			"same-hash",
+			container{Doc: hashedDoc{Name: "a", hash: 1}, Docs: []hashedDoc{{Body: "x", hash: 3}}},
+			container{Doc: hashedDoc{Name: "a", hash: 1}, Docs: []hashedDoc{{Body: "x", hash: 3}}},
+			true,
+			diff.Changelog{},
+		},
+		{
+			// a hash that doesn't change with the value hides the change. This is why
+			// TrustHash is unsafe for types whose hashes can't be relied upon
+			"wrong-hash",
+			container{Doc: hashedDoc{Name: "a", hash: 1}},
+			container{Doc: hashedDoc{Name: "b", hash: 1}},
+			true,
+			diff.Changelog{},
+		},
+		{
+			"wrong-hash-untrusted",
+			container{Doc: hashedDoc{Name: "a", hash: 1}},
+			container{Doc: hashedDoc{Name: "b", hash: 1}},
+			false,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"doc", "name"}, From: "a", To: "b"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B, diff.TrustHash(tc.Trust), diff.DiscardComplexOrigin())
+			require.Nil(t, err)
+			assert.Equal(t, tc.Changelog, cl)
+		})
+	}
+}
+
+func TestDiffValueFormatter(t *testing.T) {
+	type doc struct {
+		Level   Level     `diff:"level"`
+		Created time.Time `diff:"created"`
+		Data    []byte    `diff:"data"`
+		Note    *string   `diff:"note"`
+	}
+
+	format := func(v interface{}) string {
+		switch x := v.(type) {
+		case Level:
+			return []string{"low", "medium", "high"}[x]
+		case time.Time:
+			return x.Format("2006-01-02")
+		case []byte:
+			return strconv.Itoa(len(x)) + " bytes"
+		case *string:
+			return *x
+		}
+		return fmt.Sprint(v)
+	}
+
+	note := "note"
+	a := doc{Level: 0, Created: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), Data: []byte("ab")}
+	b := doc{Level: 2, Created: time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC), Data: []byte("abc"), Note: &note}
+
+	cl, err := diff.Diff(a, b, diff.ValueFormatter(format), diff.DiscardComplexOrigin())
+	require.Nil(t, err)
+	require.Len(t, cl, 4)
+
+	for i, expected := range [][]string{
+		{"low", "high"},
+		{"2020-01-02", "2021-03-04"},
+		{"2 bytes", "3 bytes"},
+		{"", "note"},
+	} {
+		assert.Equal(t, expected[0], cl[i].FromFormatted)
+		assert.Equal(t, expected[1], cl[i].ToFormatted)
+	}
+
+	cl, err = diff.Diff(a, b)
+	require.Nil(t, err)
+	for _, c := range cl {
+		assert.Empty(t, c.FromFormatted)
+		assert.Empty(t, c.ToFormatted)
+	}
+}
+
+func TestDiffEmbeddedPointer(t *testing.T) {
+	type embedptr struct {
+		*Embedded
+		Baz bool `diff:"baz"`
+	}
+
+	cases := []struct {
+		Name      string
+		A, B      embedptr
+		Flatten   bool
+		Changelog diff.Changelog
+	}{
+		{
+			"nil-to-populated", embedptr{}, embedptr{Embedded: &Embedded{"a", 1}}, false,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"Embedded"}, To: &Embedded{"a", 1}},
+			},
+		},
+		{
+			"populated-to-nil", embedptr{Embedded: &Embedded{"a", 1}}, embedptr{}, false,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"Embedded"}, From: &Embedded{"a", 1}},
+			},
+		},
+		{
+			"populated", embedptr{Embedded: &Embedded{"a", 1}}, embedptr{Embedded: &Embedded{"b", 1}}, false,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"Embedded", "foo"}, From: "a", To: "b"},
+			},
+		},
+		{
+			"flattened-nil-to-populated", embedptr{}, embedptr{Embedded: &Embedded{"a", 1}}, true,
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"Embedded"}, To: &Embedded{"a", 1}},
+			},
+		},
+		{
+			"flattened-populated-to-nil", embedptr{Embedded: &Embedded{"a", 1}}, embedptr{}, true,
+			diff.Changelog{
+				diff.Change{Type: diff.DELETE, Path: []string{"Embedded"}, From: &Embedded{"a", 1}},
+			},
+		},
+		{
+			"flattened-populated", embedptr{Embedded: &Embedded{"a", 1}}, embedptr{Embedded: &Embedded{"b", 1}}, true,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"foo"}, From: "a", To: "b"},
+			},
+		},
+		{
+			"flattened-nil", embedptr{Baz: true}, embedptr{}, true,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"baz"}, From: true, To: false},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			opts := []func(d *diff.Differ) error{diff.DiscardComplexOrigin()}
+			if tc.Flatten {
+				opts = append(opts, diff.FlattenEmbeddedStructs())
+			}
+
+			d, err := diff.NewDiffer(opts...)
+			require.Nil(t, err)
+
+			cl, err := d.Diff(tc.A, tc.B)
+			require.Nil(t, err)
+			assert.Equal(t, tc.Changelog, cl)
+
+			target := tc.A
+			if target.Embedded != nil {
+				e := *target.Embedded
+				target.Embedded = &e
+			}
+
+			pl := d.Patch(cl, &target)
+			assert.False(t, pl.HasErrors())
+			assert.Equal(t, tc.B, target)
+		})
+	}
+}
+
+func TestDiffPositionalStructSlices(t *testing.T) {
+	cases := []struct {
+		Name       string
+		A, B       interface{}
+		Positional bool
+		Changelog  diff.Changelog
+	}{
+		{
+			"shifted", []tuistruct{{1}, {2}}, []tuistruct{{2}, {3}}, true,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"0", "value"}, From: 1, To: 2},
+				diff.Change{Type: diff.UPDATE, Path: []string{"1", "value"}, From: 2, To: 3},
+			},
+		},
+		{
+			"shifted-by-membership", []tuistruct{{1}, {2}}, []tuistruct{{2}, {3}}, false,
+			diff.Changelog{
+				diff.Change{Type: diff.DELETE, Path: []string{"0", "value"}, From: 1},
+				diff.Change{Type: diff.CREATE, Path: []string{"1", "value"}, To: 3},
+			},
+		},
+		{
+			"nested-fields", []tmstruct{{"one", 1}, {"two", 2}}, []tmstruct{{"one", 1}, {"two", 3}}, true,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"1", "bar"}, From: 2, To: 3},
+			},
+		},
+		{
+			"pointers", []*tmstruct{{"one", 1}}, []*tmstruct{{"uno", 1}}, true,
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"0", "foo"}, From: "one", To: "uno"},
+			},
+		},
+		{
+			"different-lengths", []tuistruct{{1}, {2}}, []tuistruct{{2}}, true,
+			diff.Changelog{
+				diff.Change{Type: diff.DELETE, Path: []string{"0", "value"}, From: 1},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B, diff.PositionalStructSlices(tc.Positional), diff.DiscardComplexOrigin())
+			require.Nil(t, err)
+			assert.Equal(t, tc.Changelog, cl)
+		})
+	}
+
+	a := []tmstruct{{"one", 1}, {"two", 2}}
+	b := []tmstruct{{"uno", 1}, {"two", 3}}
+
+	cl, err := diff.Diff(a, b, diff.PositionalStructSlices(true))
+	require.Nil(t, err)
+
+	pl := diff.Patch(cl, &a)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, b, a)
+}
+
+type pair struct {
+	Key   string
+	Value interface{}
+}
+
+type orderedMap []pair
+
+func orderedMapEntries(v interface{}) ([]interface{}, []interface{}) {
+	var keys, values []interface{}
+	for _, p := range v.(orderedMap) {
+		keys = append(keys, p.Key)
+		values = append(values, p.Value)
+	}
+	return keys, values
+}
+
+func TestDiffOrderedMap(t *testing.T) {
+	type doc struct {
+		Headers orderedMap `diff:"headers"`
+	}
+
+	cases := []struct {
+		Name      string
+		A, B      doc
+		Changelog diff.Changelog
+	}{
+		{
+			"value-changed",
+			doc{orderedMap{{"a", 1}, {"b", 2}}},
+			doc{orderedMap{{"a", 1}, {"b", 3}}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"headers", "b"}, From: 2, To: 3},
+			},
+		},
+		{
+			"swapped",
+			doc{orderedMap{{"a", 1}, {"b", 2}}},
+			doc{orderedMap{{"b", 2}, {"a", 1}}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"headers"}, From: []interface{}{"a", "b"}, To: []interface{}{"b", "a"}},
+			},
+		},
+		{
+			"swapped-and-changed",
+			doc{orderedMap{{"a", 1}, {"b", 2}, {"c", 3}}},
+			doc{orderedMap{{"b", 2}, {"a", 4}, {"d", 5}}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"headers"}, From: []interface{}{"a", "b"}, To: []interface{}{"b", "a"}},
+				diff.Change{Type: diff.UPDATE, Path: []string{"headers", "a"}, From: 1, To: 4},
+				diff.Change{Type: diff.DELETE, Path: []string{"headers", "c"}, From: 3},
+				diff.Change{Type: diff.CREATE, Path: []string{"headers", "d"}, To: 5},
+			},
+		},
+		{
+			"inserted",
+			doc{orderedMap{{"a", 1}, {"b", 2}}},
+			doc{orderedMap{{"a", 1}, {"c", 3}, {"b", 2}}},
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"headers", "c"}, To: 3},
+			},
+		},
+		{
+			"created",
+			doc{},
+			doc{orderedMap{{"a", 1}}},
+			diff.Changelog{
+				diff.Change{Type: diff.CREATE, Path: []string{"headers", "a"}, To: 1},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B, diff.OrderedMap(reflect.TypeOf(orderedMap{}), orderedMapEntries), diff.DiscardComplexOrigin())
+			require.Nil(t, err)
+			assert.Equal(t, tc.Changelog, cl)
+		})
+	}
+
+	// the changes are located by key, so patching fails on each of them without touching the target
+	a := doc{orderedMap{{"a", 1}, {"b", 2}, {"c", 3}}}
+	b := doc{orderedMap{{"b", 2}, {"a", 4}, {"d", 5}}}
+
+	cl, err := diff.Diff(a, b, diff.OrderedMap(reflect.TypeOf(orderedMap{}), orderedMapEntries))
+	require.Nil(t, err)
+
+	pl := diff.Patch(cl, &a)
+	assert.Equal(t, uint(len(cl)), pl.ErrorCount())
+	assert.False(t, pl.Applied())
+	assert.Equal(t, doc{orderedMap{{"a", 1}, {"b", 2}, {"c", 3}}}, a)
+}
+
+func TestDiffMixedIdentifiedSlice(t *testing.T) {
+	warning := diff.Change{Type: diff.WARNING, Path: []string{}, To: "slice mixes elements with and without identifiers, compared by value"}
+
+	cases := []struct {
+		Name      string
+		A, B      []interface{}
+		Changelog diff.Changelog
+	}{
+		{
+			"int-first",
+			[]interface{}{1, tistruct{"one", 1}},
+			[]interface{}{1, tistruct{"one", 2}},
+			diff.Changelog{
+				warning,
+				diff.Change{Type: diff.UPDATE, Path: []string{"1", "value"}, From: 1, To: 2},
+			},
+		},
+		{
+			"identified-first",
+			[]interface{}{tistruct{"one", 1}, 1},
+			[]interface{}{tistruct{"one", 1}, 2},
+			diff.Changelog{
+				warning,
+				diff.Change{Type: diff.UPDATE, Path: []string{"1"}, From: 1, To: 2},
+			},
+		},
+		{
+			"identified",
+			[]interface{}{nil, tistruct{"one", 1}, tistruct{"two", 2}},
+			[]interface{}{tistruct{"two", 2}, tistruct{"one", 3}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"one", "value"}, From: 1, To: 3},
+			},
+		},
+		{
+			"unchanged",
+			[]interface{}{1, tistruct{"one", 1}},
+			[]interface{}{1, tistruct{"one", 1}},
+			diff.Changelog{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B, diff.DiscardComplexOrigin())
+			require.Nil(t, err)
+			assert.Equal(t, tc.Changelog, cl)
+		})
+	}
+
+	x := []interface{}{1, tistruct{"one", 1}}
+	assert.False(t, diff.Changed(x, x))
+}
+
+type version struct {
+	Major, Minor int
+	Label        string
+}
+
+// IsEqual ignores the label of versions
+func (v version) IsEqual(o version) bool {
+	return v.Major == o.Major && v.Minor == o.Minor
+}
+
+func TestDiffEqualityMethod(t *testing.T) {
+	type release struct {
+		Name    string   `diff:"name"`
+		Version version  `diff:"version"`
+		Latest  *version `diff:"latest"`
+	}
+
+	cases := []struct {
+		Name      string
+		A, B      release
+		Changelog diff.Changelog
+	}{
+		{
+			"equal", release{"a", version{1, 2, "x"}, &version{1, 2, "x"}}, release{"a", version{1, 2, "y"}, &version{1, 2, "y"}},
+			diff.Changelog{},
+		},
+		{
+			"different", release{"a", version{1, 2, "x"}, nil}, release{"a", version{1, 3, "x"}, nil},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"version"}, From: version{1, 2, "x"}, To: version{1, 3, "x"}},
+			},
+		},
+		{
+			"nil-pointer", release{"a", version{}, nil}, release{"a", version{}, &version{1, 0, ""}},
+			diff.Changelog{
+				diff.Change{Type: diff.UPDATE, Path: []string{"latest"}, To: &version{1, 0, ""}},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B, diff.EqualityMethod("IsEqual"), diff.DiscardComplexOrigin())
+			require.Nil(t, err)
+			assert.Equal(t, tc.Changelog, cl)
+		})
+	}
+
+	cl, err := diff.Diff(version{1, 2, "x"}, version{1, 2, "y"})
+	require.Nil(t, err)
+	assert.Len(t, cl, 1)
+
+	cl, err = diff.Diff(version{1, 2, "x"}, version{1, 2, "y"}, diff.EqualityMethod("Missing"))
+	require.Nil(t, err)
+	assert.Len(t, cl, 1)
+}
+
+func TestDiffReversePerspective(t *testing.T) {
+	a := tstruct{
+		Name:          "one",
+		Values:        []string{"a", "b", "c"},
+		Identifiables: []tistruct{{"one", 1}},
+	}
+	b := tstruct{
+		Name:          "two",
+		Values:        []string{"b", "c", "d"},
+		Identifiables: []tistruct{{"one", 2}, {"two", 2}},
+	}
+
+	cl, err := diff.Diff(a, b, diff.ReversePerspective(true), diff.DiscardComplexOrigin())
+	require.Nil(t, err)
+
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.UPDATE, Path: []string{"name"}, From: "two", To: "one"},
+		diff.Change{Type: diff.CREATE, Path: []string{"values", "0"}, To: "a"},
+		diff.Change{Type: diff.DELETE, Path: []string{"values", "2"}, From: "d"},
+		diff.Change{Type: diff.UPDATE, Path: []string{"identifiables", "one", "value"}, From: 2, To: 1},
+		diff.Change{Type: diff.DELETE, Path: []string{"identifiables", "two", "name"}, From: "two"},
+		diff.Change{Type: diff.DELETE, Path: []string{"identifiables", "two", "value"}, From: 2},
+	}, cl)
+
+	// the same changelog is found by diffing the other way around
+	rcl, err := diff.Diff(b, a, diff.DiscardComplexOrigin())
+	require.Nil(t, err)
+	assert.Equal(t, rcl, cl)
+}
+
+// visited counts the times it has been compared
+type visited struct {
+	count *int
+}
+
+func (v visited) DiffEqual(other interface{}) bool {
+	*v.count++
+	return true
+}
+
+func TestDiffOnlyFields(t *testing.T) {
+	type nested struct {
+		Keep  string  `diff:"keep"`
+		Skip  string  `diff:"skip"`
+		Probe visited `diff:"probe"`
+	}
+
+	type doc struct {
+		Name   string            `diff:"name"`
+		Count  int               `diff:"count"`
+		Nested nested            `diff:"nested"`
+		Labels map[string]string `diff:"labels"`
+		Probe  visited           `diff:"probe"`
+	}
+
+	count := 0
+	probe := visited{&count}
+
+	a := doc{Name: "a", Count: 1, Nested: nested{"a", "a", probe}, Labels: map[string]string{"x": "1", "y": "1"}, Probe: probe}
+	b := doc{Name: "b", Count: 2, Nested: nested{"b", "b", probe}, Labels: map[string]string{"x": "2", "y": "2"}, Probe: probe}
+
+	cl, err := diff.Diff(a, b,
+		diff.OnlyFields([]string{"name"}, []string{"nested", "keep"}, []string{"labels", "x"}),
+		diff.DiscardComplexOrigin(),
+	)
+	require.Nil(t, err)
+
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.UPDATE, Path: []string{"name"}, From: "a", To: "b"},
+		diff.Change{Type: diff.UPDATE, Path: []string{"nested", "keep"}, From: "a", To: "b"},
+		diff.Change{Type: diff.UPDATE, Path: []string{"labels", "x"}, From: "1", To: "2"},
+	}, cl)
+	assert.Equal(t, 0, count)
+
+	// without the whitelist, every field is visited
+	cl, err = diff.Diff(a, b)
+	require.Nil(t, err)
+	assert.Len(t, cl, 6)
+	assert.Equal(t, 2, count)
+}
+
+func TestDiffFlattenMaps(t *testing.T) {
+	a := map[string]interface{}{
+		"server": map[string]interface{}{
+			"http": map[string]interface{}{"port": 80, "host": "localhost"},
+			"tls":  map[string]interface{}{"enabled": false},
+		},
+		"debug": true,
+	}
+	b := map[string]interface{}{
+		"server": map[string]interface{}{
+			"http": map[string]interface{}{"port": 8080, "host": "localhost"},
+			"grpc": map[string]interface{}{"port": 9090},
+		},
+		"debug": true,
+	}
+
+	d, err := diff.NewDiffer(diff.FlattenMaps("."), diff.DiscardComplexOrigin())
+	require.Nil(t, err)
+
+	cl, err := d.Diff(a, b)
+	require.Nil(t, err)
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.CREATE, Path: []string{"server.grpc.port"}, To: 9090},
+		diff.Change{Type: diff.UPDATE, Path: []string{"server.http.port"}, From: 80, To: 8080},
+		diff.Change{Type: diff.DELETE, Path: []string{"server.tls.enabled"}, From: false},
+	}, cl)
+
+	pl := d.Patch(cl, &a)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, map[string]interface{}{
+		"server": map[string]interface{}{
+			"http": map[string]interface{}{"port": 8080, "host": "localhost"},
+			"grpc": map[string]interface{}{"port": 9090},
+			"tls":  map[string]interface{}{},
+		},
+		"debug": true,
+	}, a)
+
+	type config struct {
+		Settings map[string]interface{} `diff:"settings"`
+	}
+
+	cl, err = d.Diff(config{}, config{Settings: b})
+	require.Nil(t, err)
+	assert.Len(t, cl, 4)
+
+	var c config
+	pl = d.Patch(cl, &c)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, config{Settings: b}, c)
+
+	// keys holding the separator can't be told apart from the keys of nested maps
+	for _, ambiguous := range []map[string]interface{}{
+		{"server.http": 1},
+		{"server": map[string]interface{}{"tls.v1": true}},
+		{"server.http": 1, "server": map[string]interface{}{"http": 2}},
+	} {
+		_, err = d.Diff(a, ambiguous)
+		assert.NotNil(t, err)
+
+		_, err = d.Diff(ambiguous, a)
+		assert.NotNil(t, err)
+	}
+}
+
+type cyclic struct {
+	Name string      `diff:"name"`
+	Next *cyclic     `diff:"next"`
+	Any  interface{} `diff:"any"`
+}
+
+func TestDiffCycles(t *testing.T) {
+	a := make([]interface{}, 2)
+	a[0] = a
+	a[1] = 1
+
+	b := make([]interface{}, 2)
+	b[0] = b
+	b[1] = 2
+
+	// reflect.DeepEqual and fmt can't be used on the cyclic values, so only the paths are checked
+	cl, err := diff.Diff(a, b, diff.SliceOrdering(true), diff.DiscardComplexOrigin())
+	require.Nil(t, err)
+	require.Equal(t, 1, len(cl))
+	assert.Equal(t, []string{"1"}, cl[0].Path)
+
+	_, err = diff.Diff(a, b)
+	require.Nil(t, err)
+
+	cl, err = diff.Diff(a, a)
+	require.Nil(t, err)
+	assert.Equal(t, 0, len(cl))
+
+	ma := map[string]interface{}{"n": 1}
+	ma["self"] = ma
+	mb := map[string]interface{}{"n": 2}
+	mb["self"] = mb
+
+	cl, err = diff.Diff(ma, mb, diff.DiscardComplexOrigin())
+	require.Nil(t, err)
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.UPDATE, Path: []string{"n"}, From: 1, To: 2},
+	}, cl)
+
+	ca := &cyclic{Name: "a"}
+	ca.Next = ca
+	ca.Any = []interface{}{ca}
+	cb := &cyclic{Name: "b"}
+	cb.Next = cb
+	cb.Any = []interface{}{cb}
+
+	cl, err = diff.Diff(ca, cb, diff.DiscardComplexOrigin())
+	require.Nil(t, err)
+	require.Equal(t, 1, len(cl))
+	assert.Equal(t, []string{"name"}, cl[0].Path)
+
+	// values shared without forming a cycle are still compared wherever they appear
+	shared := &tmstruct{"one", 1}
+	other := &tmstruct{"one", 2}
+	cl, err = diff.Diff([]*tmstruct{shared, shared}, []*tmstruct{other, other}, diff.SliceOrdering(true))
+	require.Nil(t, err)
+	assert.Len(t, cl, 2)
+}
+
+func TestDiffTo(t *testing.T) {
+	type item struct {
+		Name  string   `diff:"name"`
+		Count int      `diff:"count"`
+		Tags  []string `diff:"tags"`
+	}
+
+	a := item{Name: "one", Count: 1, Tags: []string{"a"}}
+	b := item{Name: "two", Count: 2, Tags: []string{"a", "b"}}
+
+	var buf bytes.Buffer
+	err := diff.DiffTo(&buf, a, b)
+	require.Nil(t, err)
+
+	var cl diff.Changelog
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &cl))
+
+	expected, err := diff.Diff(a, b)
+	require.Nil(t, err)
+	require.Len(t, cl, len(expected))
+
+	for i := range expected {
+		assert.Equal(t, expected[i].Type, cl[i].Type)
+		assert.Equal(t, expected[i].Path, cl[i].Path)
+	}
+
+	buf.Reset()
+	err = diff.DiffTo(&buf, a, a)
+	require.Nil(t, err)
+	assert.Equal(t, "[]\n", buf.String())
+
+	err = diff.DiffTo(&buf, a, b, diff.StructWholeThreshold(2))
+	assert.NotNil(t, err)
+}
+
+func TestDiffAttachKinds(t *testing.T) {
+	type item struct {
+		Name  string `diff:"name"`
+		Count int    `diff:"count"`
+	}
+
+	type doc struct {
+		Item  item   `diff:"item"`
+		Items []item `diff:"items"`
+		Note  string `diff:"note"`
+	}
+
+	a := doc{Item: item{Name: "a", Count: 1}}
+	b := doc{Item: item{Name: "b", Count: 2}, Items: []item{{Name: "c"}}}
+
+	d, err := diff.NewDiffer(diff.AttachKinds(true), diff.StructWholeThreshold(0.7), diff.DisableStructValues())
+	require.Nil(t, err)
+
+	cl, err := d.Diff(a, b)
+	require.Nil(t, err)
+	require.Len(t, cl, 2)
+
+	assert.Equal(t, []string{"item"}, cl[0].Path)
+	assert.Equal(t, "struct", cl[0].FromKind)
+	assert.Equal(t, "struct", cl[0].ToKind)
+
+	assert.Equal(t, []string{"items", "0"}, cl[1].Path)
+	assert.Equal(t, diff.CREATE, cl[1].Type)
+	assert.Equal(t, "", cl[1].FromKind)
+	assert.Equal(t, "struct", cl[1].ToKind)
+
+	rcl := cl.Reverse()
+	assert.Equal(t, "struct", rcl[0].FromKind)
+	assert.Equal(t, "", rcl[0].ToKind)
+
+	cl, err = diff.Diff(a, b)
+	require.Nil(t, err)
+	for _, c := range cl {
+		assert.Empty(t, c.FromKind)
+		assert.Empty(t, c.ToKind)
+	}
+}
+
+func TestDiffStructShaped(t *testing.T) {
+	type address struct {
+		Street string `diff:"street"`
+		City   string `diff:"city"`
+	}
+
+	type person struct {
+		Name    string            `diff:"name"`
+		Age     int               `diff:"age"`
+		Address address           `diff:"address"`
+		Work    *address          `diff:"work"`
+		Tags    []string          `diff:"tags"`
+		Labels  map[string]string `diff:"labels"`
+		Secret  string            `diff:"-"`
+	}
+
+	a := person{Name: "a", Age: 1, Address: address{"x", "y"}, Tags: []string{"t"}}
+	b := person{Name: "b", Age: 1, Address: address{"x", "z"}, Tags: []string{"t", "u"}, Secret: "s"}
+
+	shape, err := diff.DiffStructShaped(a, b)
+	require.Nil(t, err)
+
+	cl, err := diff.Diff(a, b)
+	require.Nil(t, err)
+	require.Len(t, cl, 3)
+
+	assert.Equal(t, map[string]interface{}{
+		"name": &cl[0],
+		"age":  nil,
+		"address": map[string]interface{}{
+			"street": nil,
+			"city":   &cl[1],
+		},
+		"work": map[string]interface{}{
+			"street": nil,
+			"city":   nil,
+		},
+		"tags": map[string]interface{}{
+			"1": &cl[2],
+		},
+		"labels": nil,
+	}, shape)
+
+	_, err = diff.DiffStructShaped(1, 2)
+	assert.Equal(t, diff.ErrTypeMismatch, err)
+}
+
+func TestDiffByteArrays(t *testing.T) {
+	type uuid [16]byte
+
+	type record struct {
+		ID     uuid      `diff:"id"`
+		Hash   [4]byte   `diff:"hash"`
+		Parent *[16]byte `diff:"parent"`
+	}
+
+	a := record{
+		ID:   uuid{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8},
+		Hash: [4]byte{1, 2, 3, 4},
+	}
+	b := record{
+		ID:     uuid{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc9},
+		Hash:   [4]byte{1, 2, 3, 4},
+		Parent: &[16]byte{1},
+	}
+
+	cl, err := diff.Diff(a, b)
+	require.Nil(t, err)
+	require.Len(t, cl, 2)
+
+	assert.Equal(t, diff.UPDATE, cl[0].Type)
+	assert.Equal(t, []string{"id"}, cl[0].Path)
+	assert.Equal(t, a.ID, cl[0].From)
+	assert.Equal(t, b.ID, cl[0].To)
+
+	assert.Equal(t, []string{"parent"}, cl[1].Path)
+	assert.Equal(t, b.Parent, cl[1].To)
+
+	target := a
+	pl := diff.Patch(cl, &target)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, b, target)
+
+	d, err := diff.NewDiffer(diff.HexEncodeBytes(true))
+	require.Nil(t, err)
+
+	cl, err = d.Diff(a, b)
+	require.Nil(t, err)
+	require.Len(t, cl, 2)
+	assert.Equal(t, "6ba7b8109dad11d180b400c04fd430c8", cl[0].From)
+	assert.Equal(t, "6ba7b8119dad11d180b400c04fd430c9", cl[0].To)
+
+	target = a
+	pl = d.Patch(cl, &target)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, b, target)
+}
+
+func TestDiffDereferencePointers(t *testing.T) {
+	type point struct {
+		X int `diff:"x"`
+		Y int `diff:"y"`
+	}
+
+	type shape struct {
+		Origin interface{} `diff:"origin"`
+	}
+
+	_, err := diff.Diff(point{1, 2}, &point{1, 2})
+	assert.Equal(t, diff.ErrTypeMismatch, err)
+
+	d, err := diff.NewDiffer(diff.DereferencePointers(true))
+	require.Nil(t, err)
+
+	cases := []struct {
+		Name      string
+		A, B      interface{}
+		Changelog diff.Changelog
+	}{
+		{"value-to-pointer", point{1, 2}, &point{1, 2}, nil},
+		{"pointer-to-value", &point{1, 2}, point{1, 3}, diff.Changelog{
+			diff.Change{Type: diff.UPDATE, Path: []string{"y"}, From: 2, To: 3},
+		}},
+		{"double-pointer", point{1, 2}, func() **point { p := &point{2, 2}; return &p }(), diff.Changelog{
+			diff.Change{Type: diff.UPDATE, Path: []string{"x"}, From: 1, To: 2},
+		}},
+		{"interface-value-to-pointer", shape{point{1, 2}}, shape{&point{3, 2}}, diff.Changelog{
+			diff.Change{Type: diff.UPDATE, Path: []string{"origin", "x"}, From: 1, To: 3},
+		}},
+		{"interface-nil-pointer-to-value", shape{(*point)(nil)}, shape{point{1, 2}}, diff.Changelog{
+			diff.Change{Type: diff.UPDATE, Path: []string{"origin"}, From: nil, To: point{1, 2}},
+		}},
+		{"interface-value-to-nil-pointer", shape{point{1, 2}}, shape{(*point)(nil)}, diff.Changelog{
+			diff.Change{Type: diff.UPDATE, Path: []string{"origin"}, From: point{1, 2}, To: nil},
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := d.Diff(tc.A, tc.B)
+			require.Nil(t, err)
+
+			require.Len(t, cl, len(tc.Changelog))
+			for i, c := range cl {
+				assert.Equal(t, tc.Changelog[i].Type, c.Type)
+				assert.Equal(t, tc.Changelog[i].Path, c.Path)
+				assert.Equal(t, tc.Changelog[i].From, c.From)
+				assert.Equal(t, tc.Changelog[i].To, c.To)
+			}
+		})
+	}
+}
+
+func TestDiffIgnoreByTag(t *testing.T) {
+	type entry struct {
+		Text      string `diff:"text"`
+		UpdatedBy string `diff:"updated_by" audit:"false"`
+	}
+
+	type document struct {
+		Title    string  `diff:"title"`
+		Revision int     `diff:"revision" audit:"false"`
+		Owner    string  `diff:"owner" audit:"true"`
+		Entries  []entry `diff:"entries"`
+		Internal string  `diff:"internal"`
+	}
+
+	a := document{Title: "a", Revision: 1, Owner: "x", Entries: []entry{{"one", "x"}}, Internal: "i"}
+	b := document{Title: "b", Revision: 2, Owner: "y", Entries: []entry{{"two", "y"}}, Internal: "j"}
+
+	cl, err := diff.Diff(a, b, diff.IgnoreByTag("audit", "false"))
+	require.Nil(t, err)
+	assert.Equal(t, [][]string{{"title"}, {"owner"}, {"entries", "0", "text"}, {"internal"}}, cl.Paths())
+
+	// combined with a filter set before it
+	internal := func(path []string, parent reflect.Type, field reflect.StructField) bool {
+		return field.Name != "Internal"
+	}
+
+	cl, err = diff.Diff(a, b, diff.Filter(internal), diff.IgnoreByTag("audit", "false"))
+	require.Nil(t, err)
+	assert.Equal(t, [][]string{{"title"}, {"owner"}, {"entries", "0", "text"}}, cl.Paths())
+}
+
+func TestDiffStream(t *testing.T) {
+	type item struct {
+		ID     string `diff:"id,identifier"`
+		Value  int    `diff:"value"`
+		Secret string `diff:"secret,redact"`
+	}
+
+	type doc struct {
+		Name   string            `diff:"name"`
+		Items  []item            `diff:"items"`
+		Labels map[string]string `diff:"labels"`
+		Extra  *item             `diff:"extra"`
+	}
+
+	a := doc{
+		Name:  "a",
+		Items: []item{{"1", 1, "x"}, {"2", 2, "y"}},
+	}
+	b := doc{
+		Name:   "b",
+		Items:  []item{{"2", 3, "z"}, {"3", 1, "w"}},
+		Labels: map[string]string{"env": "prod", "team": "core"},
+		Extra:  &item{"4", 4, "v"},
+	}
+
+	opts := [][]func(d *diff.Differ) error{
+		nil,
+		{diff.TrackComparativeIndices(true)},
+		{diff.StructWholeThreshold(0.5)},
+		{diff.AttachKinds(true), diff.MaxValueBytes(8)},
+	}
+
+	for _, o := range opts {
+		d, err := diff.NewDiffer(o...)
+		require.Nil(t, err)
+
+		expected, err := d.Diff(a, b)
+		require.Nil(t, err)
+		require.NotEmpty(t, expected)
+
+		var cl diff.Changelog
+		err = d.DiffStream(a, b, func(c diff.Change) error {
+			cl = append(cl, c)
+			return nil
+		})
+		require.Nil(t, err)
+
+		require.Len(t, cl, len(expected))
+		for i := range expected {
+			assert.Equal(t, expected[i].Type, cl[i].Type)
+			assert.Equal(t, expected[i].Path, cl[i].Path)
+			assert.Equal(t, expected[i].From, cl[i].From)
+			assert.Equal(t, expected[i].To, cl[i].To)
+			assert.Equal(t, expected[i].FromIndex, cl[i].FromIndex)
+			assert.Equal(t, expected[i].ToKind, cl[i].ToKind)
+		}
+	}
+
+	// an error from emit stops the diff
+	errStop := errors.New("stop")
+	count := 0
+
+	d, err := diff.NewDiffer()
+	require.Nil(t, err)
+
+	err = d.DiffStream(a, b, func(c diff.Change) error {
+		count++
+		if count == 2 {
+			return errStop
+		}
+		return nil
+	})
+	assert.Equal(t, errStop, err)
+	assert.Equal(t, 2, count)
+
+	// the differ can still be used to collect changes
+	cl, err := d.Diff(a, b)
+	require.Nil(t, err)
+	assert.NotEmpty(t, cl)
+}
+
+func TestDiffStreamEndMarker(t *testing.T) {
+	type doc struct {
+		Name   string            `diff:"name"`
+		Tags   []string          `diff:"tags"`
+		Labels map[string]string `diff:"labels"`
+	}
+
+	a := doc{Name: "a", Tags: []string{"x", "y"}, Labels: map[string]string{"env": "dev"}}
+	b := doc{Name: "b", Tags: []string{"x", "z"}, Labels: map[string]string{"team": "core"}}
+
+	d, err := diff.NewDiffer(diff.EmitEndMarker(true))
+	require.Nil(t, err)
+
+	var cl diff.Changelog
+	err = d.DiffStream(a, b, func(c diff.Change) error {
+		cl = append(cl, c)
+		return nil
+	})
+	require.Nil(t, err)
+	require.Len(t, cl, 5)
+
+	for _, c := range cl[:len(cl)-1] {
+		assert.NotEqual(t, diff.END, c.Type)
+	}
+
+	end := cl[len(cl)-1]
+	assert.Equal(t, diff.END, end.Type)
+	require.IsType(t, diff.StreamSummary{}, end.To)
+
+	sum := end.To.(diff.StreamSummary)
+	assert.Equal(t, diff.DiffStats{Creates: 1, Updates: 2, Deletes: 1}, sum.Stats)
+	assert.Equal(t, cl[:len(cl)-1].Stats(), sum.Stats)
+	assert.True(t, sum.Duration > 0)
+
+	// the marker isn't counted as a change, nor applied by patch
+	n, err := d.CountChanges(a, b)
+	require.Nil(t, err)
+	assert.Equal(t, 4, n)
+
+	pl := diff.Patch(cl, &a)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, b, a)
+
+	// no marker is emitted when the diff fails
+	errStop := errors.New("stop")
+	err = d.DiffStream(doc{Name: "a"}, doc{Name: "b"}, func(c diff.Change) error {
+		assert.NotEqual(t, diff.END, c.Type)
+		return errStop
+	})
+	assert.Equal(t, errStop, err)
+}
+
+func TestDiffLazy(t *testing.T) {
+	type address struct {
+		Street string `diff:"street"`
+		City   string `diff:"city"`
+	}
+
+	type person struct {
+		Name    string            `diff:"name"`
+		Age     int               `diff:"age"`
+		Address *address          `diff:"address"`
+		Tags    []string          `diff:"tags"`
+		Labels  map[string]string `diff:"labels"`
+		Ignored string            `diff:"-"`
+	}
+
+	a := person{
+		Name:    "a",
+		Age:     1,
+		Address: &address{"x", "y"},
+		Tags:    []string{"t"},
+		Labels:  map[string]string{"env": "dev", "team": "core"},
+	}
+
+	// the fields of b, as they would be fetched from elsewhere
+	b := map[string]interface{}{
+		"name":           "b",
+		"age":            1,
+		"address.street": "x",
+		"address.city":   "z",
+		"tags":           []string{"t", "u"},
+		"labels.env":     "prod",
+	}
+
+	var fetched []string
+	get := func(path []string) (interface{}, bool) {
+		p := strings.Join(path, ".")
+		fetched = append(fetched, p)
+		v, ok := b[p]
+		return v, ok
+	}
+
+	d, err := diff.NewDiffer()
+	require.Nil(t, err)
+
+	cl, err := d.DiffLazy(a, get)
+	require.Nil(t, err)
+
+	assert.Equal(t, []string{"name", "age", "address.street", "address.city", "tags", "labels.env", "labels.team"}, fetched)
+
+	expected, err := diff.Diff(a, person{
+		Name:    "b",
+		Age:     1,
+		Address: &address{"x", "z"},
+		Tags:    []string{"t", "u"},
+		Labels:  map[string]string{"env": "prod"},
+	})
+	require.Nil(t, err)
+
+	require.Len(t, cl, len(expected))
+	for i := range expected {
+		assert.Equal(t, expected[i].Type, cl[i].Type)
+		assert.Equal(t, expected[i].Path, cl[i].Path)
+		assert.Equal(t, expected[i].From, cl[i].From)
+		assert.Equal(t, expected[i].To, cl[i].To)
+	}
+
+	// mismatched types are reported as with Diff
+	_, err = d.DiffLazy(a, func(path []string) (interface{}, bool) {
+		return int64(1), true
+	})
+	assert.Equal(t, diff.ErrTypeMismatch, err)
+}
+
+type contact struct {
+	First string `diff:"first"`
+	Last  string `diff:"last"`
+	Email string `diff:"-"`
+}
+
+func (c contact) FullName() string {
+	return c.First + " " + c.Last
+}
+
+func TestDiffComputedField(t *testing.T) {
+	type book struct {
+		Title  string  `diff:"title"`
+		Author contact `diff:"author"`
+	}
+
+	fullName := func(v interface{}) interface{} {
+		if c, ok := v.(contact); ok {
+			return c.FullName()
+		}
+		return nil
+	}
+
+	a := book{Title: "a", Author: contact{First: "Jane", Last: "Doe", Email: "x"}}
+	b := book{Title: "a", Author: contact{First: "Jane", Last: "Smith", Email: "y"}}
+
+	d, err := diff.NewDiffer(diff.ComputedField("full_name", fullName))
+	require.Nil(t, err)
+
+	cl, err := d.Diff(a, b)
+	require.Nil(t, err)
+	require.Len(t, cl, 2)
+
+	assert.Equal(t, []string{"author", "last"}, cl[0].Path)
+	assert.Equal(t, diff.UPDATE, cl[1].Type)
+	assert.Equal(t, []string{"author", "full_name"}, cl[1].Path)
+	assert.Equal(t, "Jane Doe", cl[1].From)
+	assert.Equal(t, "Jane Smith", cl[1].To)
+
+	// only the email has changed, which doesn't affect the computed name
+	b = book{Title: "a", Author: contact{First: "Jane", Last: "Doe", Email: "y"}}
+	cl, err = d.Diff(a, b)
+	require.Nil(t, err)
+	assert.Len(t, cl, 0)
+}
+
+func TestDiffUnsupportedKinds(t *testing.T) {
+	type worker struct {
+		Name  string        `diff:"name"`
+		Done  chan struct{} `diff:"done"`
+		Count int           `diff:"count"`
 	}
 
-	for _, tc := range cases {
-		t.Run(tc.Name, func(t *testing.T) {
-			ncl := cl.Filter(tc.Filter)
-			assert.Len(t, ncl, len(tc.Expected))
-			for i, e := range tc.Expected {
-				assert.Equal(t, e, ncl[i].Path)
-			}
-		})
+	a := worker{Name: "a", Done: make(chan struct{}), Count: 1}
+	b := worker{Name: "b", Done: make(chan struct{}), Count: 2}
+
+	cl, err := diff.Diff(a, b)
+	require.Nil(t, err)
+	assert.Equal(t, [][]string{{"name"}, {"count"}}, cl.Paths())
+
+	cl, err = diff.Diff(map[string]worker{}, map[string]worker{"w": a})
+	require.Nil(t, err)
+	assert.Equal(t, [][]string{{"w", "name"}, {"w", "count"}}, cl.Paths())
+
+	type job struct {
+		Name     string `diff:"name"`
+		Callback func() `diff:"callback"`
 	}
+
+	_, err = diff.Diff(job{Name: "a"}, job{Name: "b"})
+	assert.EqualError(t, err, "unsupported type: func")
+
+	cl, err = diff.Diff(job{Name: "a"}, job{Name: "b", Callback: func() {}}, diff.IgnoreUnsupported(true))
+	require.Nil(t, err)
+	assert.Equal(t, [][]string{{"name"}}, cl.Paths())
 }
 
-func TestFilterOut(t *testing.T) {
-	cases := []struct {
-		Name     string
-		Filter   []string
-		Expected [][]string
-	}{
-		{"simple", []string{"item-1", "subitem"}, [][]string{{"item-2", "subitem"}}},
-		{"regex", []string{"item-*"}, [][]string{}},
+func TestDiffFuzzySliceMatch(t *testing.T) {
+	a := []float64{1.0, 2.0, 3.0, 10.0}
+	b := []float64{3.05, 1.02, 2.01, 20.0, 4.0}
+
+	cl, err := diff.Diff(a, b, diff.FuzzySliceMatch(0.1))
+	require.Nil(t, err)
+
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.UPDATE, Path: []string{"0"}, From: 1.0, To: 1.02},
+		diff.Change{Type: diff.UPDATE, Path: []string{"1"}, From: 2.0, To: 2.01},
+		diff.Change{Type: diff.UPDATE, Path: []string{"2"}, From: 3.0, To: 3.05},
+		diff.Change{Type: diff.UPDATE, Path: []string{"3"}, From: 10.0, To: 20.0},
+		diff.Change{Type: diff.CREATE, Path: []string{"4"}, To: 4.0},
+	}, cl)
+
+	target := append([]float64{}, a...)
+	pl := diff.Patch(cl, &target)
+	assert.False(t, pl.HasErrors())
+	assert.ElementsMatch(t, b, target)
+
+	// each element is paired with the nearest one
+	cl, err = diff.Diff([]int{10, 20}, []int{19, 11}, diff.FuzzySliceMatch(2))
+	require.Nil(t, err)
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.UPDATE, Path: []string{"0"}, From: 10, To: 11},
+		diff.Change{Type: diff.UPDATE, Path: []string{"1"}, From: 20, To: 19},
+	}, cl)
+
+	// several elements may be left unpaired
+	cl, err = diff.Diff([]float64{1, 100, 200}, []float64{1.05}, diff.FuzzySliceMatch(0.1))
+	require.Nil(t, err)
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.UPDATE, Path: []string{"0"}, From: 1.0, To: 1.05},
+		diff.Change{Type: diff.DELETE, Path: []string{"1"}, From: 100.0},
+		diff.Change{Type: diff.DELETE, Path: []string{"2"}, From: 200.0},
+	}, cl)
+
+	cl, err = diff.Diff([]float64{1, 100, 200, 5}, []float64{1.05, 300, 400}, diff.FuzzySliceMatch(0.1))
+	require.Nil(t, err)
+	assert.Equal(t, diff.Changelog{
+		diff.Change{Type: diff.UPDATE, Path: []string{"0"}, From: 1.0, To: 1.05},
+		diff.Change{Type: diff.UPDATE, Path: []string{"1"}, From: 100.0, To: 300.0},
+		diff.Change{Type: diff.UPDATE, Path: []string{"2"}, From: 200.0, To: 400.0},
+		diff.Change{Type: diff.DELETE, Path: []string{"3"}, From: 5.0},
+	}, cl)
+
+	// without a threshold, elements are paired by index
+	cl, err = diff.Diff(a, b)
+	require.Nil(t, err)
+	assert.Equal(t, diff.Change{Type: diff.UPDATE, Path: []string{"0"}, From: 1.0, To: 3.05}, cl[0])
+
+	_, err = diff.NewDiffer(diff.FuzzySliceMatch(-1))
+	assert.NotNil(t, err)
+}
+
+func TestCountChanges(t *testing.T) {
+	type item struct {
+		Name   string            `diff:"name"`
+		Values []int             `diff:"values"`
+		Labels map[string]string `diff:"labels"`
 	}
 
-	cl := diff.Changelog{
-		{Path: []string{"item-1", "subitem"}},
-		{Path: []string{"item-2", "subitem"}},
+	a := item{Name: "a", Values: []int{1, 2}, Labels: map[string]string{"env": "dev"}}
+	b := item{Name: "b", Values: []int{1, 3, 4}, Labels: map[string]string{"env": "prod", "team": "core"}}
+
+	cl, err := diff.Diff(a, b)
+	require.Nil(t, err)
+
+	n, err := diff.CountChanges(a, b)
+	require.Nil(t, err)
+	assert.Equal(t, len(cl), n)
+
+	// unchanged values aren't counted
+	n, err = diff.CountChanges(a, b, diff.IncludeUnchanged(true))
+	require.Nil(t, err)
+	assert.Equal(t, len(cl), n)
+
+	n, err = diff.CountChanges(a, a)
+	require.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	_, err = diff.CountChanges(a, 1)
+	assert.Equal(t, diff.ErrTypeMismatch, err)
+}
+
+func BenchmarkCountChanges(b *testing.B) {
+	type point struct {
+		X int `diff:"x"`
+		Y int `diff:"y"`
 	}
 
-	for _, tc := range cases {
-		t.Run(tc.Name, func(t *testing.T) {
-			ncl := cl.FilterOut(tc.Filter)
-			assert.Len(t, ncl, len(tc.Expected))
-			for i, e := range tc.Expected {
-				assert.Equal(t, e, ncl[i].Path)
-			}
-		})
+	from := make(map[string]point)
+	to := make(map[string]point)
+	for i := 0; i < 1000; i++ {
+		from[strconv.Itoa(i)] = point{i, i}
+		to[strconv.Itoa(i)] = point{i, i + 1}
 	}
+
+	b.Run("diff", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			cl, _ := diff.Diff(from, to)
+			_ = len(cl)
+		}
+	})
+
+	b.Run("count", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = diff.CountChanges(from, to)
+		}
+	})
 }
 
-func TestStructValues(t *testing.T) {
+func TestDiffBigNumbers(t *testing.T) {
+	type account struct {
+		Balance *big.Int   `diff:"balance"`
+		Rate    *big.Rat   `diff:"rate"`
+		Ratio   *big.Float `diff:"ratio"`
+	}
+
 	cases := []struct {
-		Name       string
-		ChangeType string
-		X          interface{}
-		Changelog  diff.Changelog
-		Error      error
+		Name      string
+		A, B      account
+		Changelog diff.Changelog
 	}{
 		{
-			"struct-create", diff.CREATE, tstruct{ID: "xxxxx", Name: "something", Value: 1, Values: []string{"one", "two", "three"}},
+			"equal", account{Balance: big.NewInt(100), Rate: big.NewRat(1, 2), Ratio: big.NewFloat(0.5)},
+			account{Balance: big.NewInt(100), Rate: big.NewRat(2, 4), Ratio: new(big.Float).SetPrec(200).SetFloat64(0.5)},
+			diff.Changelog{},
+		},
+		{
+			"update", account{Balance: big.NewInt(100), Rate: big.NewRat(1, 2)}, account{Balance: big.NewInt(101), Rate: big.NewRat(1, 2)},
 			diff.Changelog{
-				diff.Change{Type: diff.CREATE, Path: []string{"id"}, From: nil, To: "xxxxx"},
-				diff.Change{Type: diff.CREATE, Path: []string{"name"}, From: nil, To: "something"},
-				diff.Change{Type: diff.CREATE, Path: []string{"value"}, From: nil, To: 1},
-				diff.Change{Type: diff.CREATE, Path: []string{"values", "0"}, From: nil, To: "one"},
-				diff.Change{Type: diff.CREATE, Path: []string{"values", "1"}, From: nil, To: "two"},
-				diff.Change{Type: diff.CREATE, Path: []string{"values", "2"}, From: nil, To: "three"},
+				diff.Change{Type: diff.UPDATE, Path: []string{"balance"}, From: big.NewInt(100), To: big.NewInt(101)},
 			},
-			nil,
 		},
 		{
-			"struct-delete", diff.DELETE, tstruct{ID: "xxxxx", Name: "something", Value: 1, Values: []string{"one", "two", "three"}},
+			"create", account{}, account{Rate: big.NewRat(1, 3)},
 			diff.Changelog{
-				diff.Change{Type: diff.DELETE, Path: []string{"id"}, From: "xxxxx", To: nil},
-				diff.Change{Type: diff.DELETE, Path: []string{"name"}, From: "something", To: nil},
-				diff.Change{Type: diff.DELETE, Path: []string{"value"}, From: 1, To: nil},
-				diff.Change{Type: diff.DELETE, Path: []string{"values", "0"}, From: "one", To: nil},
-				diff.Change{Type: diff.DELETE, Path: []string{"values", "1"}, From: "two", To: nil},
-				diff.Change{Type: diff.DELETE, Path: []string{"values", "2"}, From: "three", To: nil},
+				diff.Change{Type: diff.CREATE, Path: []string{"rate"}, To: big.NewRat(1, 3)},
+			},
+		},
+		{
+			"delete", account{Ratio: big.NewFloat(1.5)}, account{},
+			diff.Changelog{
+				diff.Change{Type: diff.DELETE, Path: []string{"ratio"}, From: big.NewFloat(1.5)},
 			},
-			nil,
 		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
-			cl, err := diff.StructValues(tc.ChangeType, []string{}, tc.X)
+			cl, err := diff.Diff(tc.A, tc.B, diff.DiscardComplexOrigin())
+			require.Nil(t, err)
+			assert.Equal(t, tc.Changelog, cl)
 
-			assert.Equal(t, tc.Error, err)
-			assert.Equal(t, len(tc.Changelog), len(cl))
+			target := tc.A
+			pl := diff.Patch(cl, &target)
+			assert.False(t, pl.HasErrors())
+			assert.Equal(t, tc.B.Balance, target.Balance)
+		})
+	}
+}
+
+func TestDiffNilEqualsZeroStruct(t *testing.T) {
+	type inner struct {
+		Name string `diff:"name"`
+	}
+
+	type outer struct {
+		Inner *inner      `diff:"inner"`
+		Value interface{} `diff:"value"`
+	}
+
+	d, err := diff.NewDiffer(diff.NilEqualsZeroStruct(true))
+	require.Nil(t, err)
+
+	cases := []struct {
+		Name    string
+		A, B    outer
+		Changes int
+	}{
+		{"nil-to-zero", outer{}, outer{Inner: &inner{}}, 0},
+		{"zero-to-nil", outer{Inner: &inner{}}, outer{}, 0},
+		{"nil-to-value", outer{}, outer{Inner: &inner{"a"}}, 1},
+		{"value-to-nil", outer{Inner: &inner{"a"}}, outer{}, 1},
+		{"nil-pointer-to-zero-value", outer{Value: (*inner)(nil)}, outer{Value: inner{}}, 0},
+		{"zero-value-to-nil-pointer", outer{Value: inner{}}, outer{Value: (*inner)(nil)}, 0},
+		{"nil-pointer-to-value", outer{Value: (*inner)(nil)}, outer{Value: inner{"a"}}, 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := d.Diff(tc.A, tc.B)
+			require.Nil(t, err)
+			assert.Len(t, cl, tc.Changes)
+
+			// without the option, nil and zero structs differ
+			cl, err = diff.Diff(tc.A, tc.B)
+			require.Nil(t, err)
+			assert.Len(t, cl, 1)
+		})
+	}
+}
+
+func TestUnchangedSubtrees(t *testing.T) {
+	type address struct {
+		Street string `diff:"street"`
+		City   string `diff:"city"`
+	}
+
+	type profile struct {
+		Bio     string   `diff:"bio"`
+		Address address  `diff:"address"`
+		Links   []string `diff:"links"`
+	}
+
+	type user struct {
+		Name     string            `diff:"name"`
+		Profile  *profile          `diff:"profile"`
+		Settings map[string]string `diff:"settings"`
+		Billing  address           `diff:"billing"`
+	}
+
+	a := user{
+		Name:     "a",
+		Profile:  &profile{Bio: "x", Address: address{"1 Main St", "Dublin"}, Links: []string{"l"}},
+		Settings: map[string]string{"theme": "dark"},
+		Billing:  address{"2 High St", "Cork"},
+	}
+	b := user{
+		Name:     "a",
+		Profile:  &profile{Bio: "x", Address: address{"1 Main St", "Galway"}, Links: []string{"l"}},
+		Settings: map[string]string{"theme": "dark"},
+		Billing:  address{"2 High St", "Cork"},
+	}
+
+	roots, err := diff.UnchangedSubtrees(a, b)
+	require.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"name"},
+		{"profile", "bio"},
+		{"profile", "address", "street"},
+		{"profile", "links"},
+		{"settings"},
+		{"billing"},
+	}, roots)
+
+	roots, err = diff.UnchangedSubtrees(a, a)
+	require.Nil(t, err)
+	assert.Equal(t, [][]string{{"name"}, {"profile"}, {"settings"}, {"billing"}}, roots)
+
+	type job struct {
+		Run func() `diff:"run"`
+	}
+
+	_, err = diff.UnchangedSubtrees(job{}, job{})
+	assert.EqualError(t, err, "unsupported type: func")
+}
+
+func TestDiffOmitZeroCreates(t *testing.T) {
+	type address struct {
+		City string `diff:"city"`
+		Zip  string `diff:"zip"`
+	}
+	type account struct {
+		Name    string      `diff:"name"`
+		Age     int         `diff:"age"`
+		Active  bool        `diff:"active"`
+		Extra   interface{} `diff:"extra"`
+		Address address     `diff:"address"`
+	}
+
+	a := map[string]account{}
+	b := map[string]account{"account": {Name: "alice", Active: true, Extra: 0, Address: address{City: "Paris"}}}
+
+	cl, err := diff.Diff(a, b)
+	require.NoError(t, err)
+	assert.Len(t, cl, 4)
+
+	cl, err = diff.Diff(a, b, diff.IncludeUnchanged(true))
+	require.NoError(t, err)
+	assert.Len(t, cl, 6)
+
+	for _, opts := range [][]func(d *diff.Differ) error{
+		{diff.OmitZeroCreates(true)},
+		{diff.OmitZeroCreates(true), diff.IncludeUnchanged(true)},
+	} {
+		cl, err = diff.Diff(a, b, opts...)
+		require.NoError(t, err)
+		require.Len(t, cl, 3)
+		assert.Equal(t, []string{"account", "name"}, cl[0].Path)
+		assert.Equal(t, "alice", cl[0].To)
+		assert.Equal(t, []string{"account", "active"}, cl[1].Path)
+		assert.Equal(t, true, cl[1].To)
+		assert.Equal(t, []string{"account", "address", "city"}, cl[2].Path)
+		assert.Equal(t, "Paris", cl[2].To)
+	}
+
+	// deletions of zero values are kept
+	cl, err = diff.Diff(b, a, diff.OmitZeroCreates(true))
+	require.NoError(t, err)
+	assert.Len(t, cl, 4)
+}
+
+func TestDiffIP(t *testing.T) {
+	_, n1, _ := net.ParseCIDR("10.0.0.0/8")
+	_, n2, _ := net.ParseCIDR("10.0.0.0/8")
+	_, n3, _ := net.ParseCIDR("10.0.0.0/16")
+
+	cases := []struct {
+		Name      string
+		A, B      interface{}
+		Changelog diff.Changelog
+	}{
+		{"ipv4-4-and-16-byte-forms", net.IPv4(1, 2, 3, 4), net.IPv4(1, 2, 3, 4).To4(), diff.Changelog{}},
+		{"ipv6-equal", net.ParseIP("2001:db8::1"), net.ParseIP("2001:0db8:0:0:0:0:0:1"), diff.Changelog{}},
+		{"ipv4-changed", net.ParseIP("1.2.3.4"), net.ParseIP("1.2.3.5"), diff.Changelog{
+			{Type: diff.UPDATE, Path: []string{}, From: "1.2.3.4", To: "1.2.3.5"},
+		}},
+		{"ipv4-to-ipv6", net.ParseIP("1.2.3.4"), net.ParseIP("::1"), diff.Changelog{
+			{Type: diff.UPDATE, Path: []string{}, From: "1.2.3.4", To: "::1"},
+		}},
+		{"nil-and-empty", net.IP(nil), net.IP{}, diff.Changelog{}},
+		{"created", net.IP{}, net.ParseIP("1.2.3.4"), diff.Changelog{
+			{Type: diff.CREATE, Path: []string{}, To: "1.2.3.4"},
+		}},
+		{"deleted", net.ParseIP("1.2.3.4"), net.IP(nil), diff.Changelog{
+			{Type: diff.DELETE, Path: []string{}, From: "1.2.3.4"},
+		}},
+		{"network-equal", n1, n2, diff.Changelog{}},
+		{"network-changed", n1, n3, diff.Changelog{
+			{Type: diff.UPDATE, Path: []string{}, From: "10.0.0.0/8", To: "10.0.0.0/16"},
+		}},
+		{"network-nil", (*net.IPNet)(nil), n1, diff.Changelog{
+			{Type: diff.CREATE, Path: []string{}, To: "10.0.0.0/8"},
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cl, err := diff.Diff(tc.A, tc.B)
+			require.Nil(t, err)
+			require.Len(t, cl, len(tc.Changelog))
 
 			for i, c := range cl {
 				assert.Equal(t, tc.Changelog[i].Type, c.Type)
@@ -841,53 +4807,180 @@ func TestStructValues(t *testing.T) {
 			}
 		})
 	}
+
+	type host struct {
+		Address net.IP     `diff:"address"`
+		Network *net.IPNet `diff:"network"`
+	}
+
+	a := host{Address: net.ParseIP("1.2.3.4"), Network: n1}
+	b := host{Address: net.ParseIP("1.2.3.5"), Network: n3}
+
+	cl, err := diff.Diff(a, b)
+	require.Nil(t, err)
+	require.Len(t, cl, 2)
+
+	pl := diff.Patch(cl, &a)
+	assert.False(t, pl.HasErrors())
+	assert.True(t, b.Address.Equal(a.Address))
+	assert.Equal(t, b.Network.String(), a.Network.String())
 }
 
-func TestDifferReuse(t *testing.T) {
-	d, err := diff.NewDiffer()
+func TestDiffIncludeUnexported(t *testing.T) {
+	type settings struct {
+		Theme string
+		size  int
+	}
+	type account struct {
+		Name     string
+		settings settings
+		backup   *settings
+		history  []settings
+		count    int8
+	}
+
+	a := account{
+		Name:     "a",
+		settings: settings{"dark", 1},
+		backup:   &settings{"dark", 1},
+		history:  []settings{{"light", 2}},
+		count:    1,
+	}
+	b := account{
+		Name:     "a",
+		settings: settings{"light", 2},
+		backup:   &settings{"dark", 3},
+		history:  []settings{{"light", 4}},
+		count:    2,
+	}
+
+	// structs held by unexported fields are skipped, and unexported values lose their type
+	cl, err := diff.Diff(a, b)
 	require.Nil(t, err)
+	require.Len(t, cl, 1)
+	assert.Equal(t, []string{"count"}, cl[0].Path)
+	assert.Equal(t, int64(1), cl[0].From)
 
-	cl, err := d.Diff([]string{"1", "2", "3"}, []string{"1"})
+	cl, err = diff.Diff(a, b, diff.IncludeUnexported(true))
 	require.Nil(t, err)
 
-	require.Len(t, cl, 2)
+	expected := diff.Changelog{
+		{Type: diff.UPDATE, Path: []string{"settings", "Theme"}, From: "dark", To: "light"},
+		{Type: diff.UPDATE, Path: []string{"settings", "size"}, From: 1, To: 2},
+		{Type: diff.UPDATE, Path: []string{"backup", "size"}, From: 1, To: 3},
+		{Type: diff.UPDATE, Path: []string{"history", "0", "size"}, From: 2, To: 4},
+		{Type: diff.UPDATE, Path: []string{"count"}, From: int8(1), To: int8(2)},
+	}
 
-	assert.Equal(t, "2", cl[0].From)
-	assert.Equal(t, nil, cl[0].To)
-	assert.Equal(t, "3", cl[1].From)
-	assert.Equal(t, nil, cl[1].To)
+	require.Len(t, cl, len(expected))
+	for i, c := range cl {
+		assert.Equal(t, expected[i].Type, c.Type)
+		assert.Equal(t, expected[i].Path, c.Path)
+		assert.Equal(t, expected[i].From, c.From)
+		assert.Equal(t, expected[i].To, c.To)
+	}
 
-	cl, err = d.Diff([]string{"a", "b"}, []string{"a", "c"})
+	// nil pointers held by unexported fields are compared as well
+	b.backup = nil
+	cl, err = diff.Diff(a, b, diff.IncludeUnexported(true))
 	require.Nil(t, err)
+	require.Len(t, cl, 5)
+	assert.Equal(t, []string{"backup"}, cl[2].Path)
+	assert.Equal(t, a.backup, cl[2].From)
+	assert.Nil(t, cl[2].To)
+}
 
-	require.Len(t, cl, 1)
+func TestDiffCompositeIdentifier(t *testing.T) {
+	type server struct {
+		Region string `diff:"region,identifier"`
+		Name   string `diff:"name,identifier"`
+		Size   int    `diff:"size"`
+	}
 
-	assert.Equal(t, "b", cl[0].From)
-	assert.Equal(t, "c", cl[0].To)
-}
+	a := []server{{"eu", "web", 1}, {"us", "web", 1}, {"eu", "db", 1}, {"us", "cache", 1}}
+	b := []server{{"us", "web", 2}, {"eu", "web", 1}, {"eu", "db", 3}, {"us", "db", 1}}
 
-func TestDiffingOptions(t *testing.T) {
-	d, err := diff.NewDiffer(diff.SliceOrdering(false))
+	cl, err := diff.Diff(a, b)
 	require.Nil(t, err)
 
-	assert.False(t, d.SliceOrdering)
+	expected := diff.Changelog{
+		{Type: diff.UPDATE, Path: []string{"eu,db", "size"}, From: 1, To: 3},
+		{Type: diff.DELETE, Path: []string{"us,cache", "region"}, From: "us"},
+		{Type: diff.DELETE, Path: []string{"us,cache", "name"}, From: "cache"},
+		{Type: diff.DELETE, Path: []string{"us,cache", "size"}, From: 1},
+		{Type: diff.CREATE, Path: []string{"us,db", "region"}, To: "us"},
+		{Type: diff.CREATE, Path: []string{"us,db", "name"}, To: "db"},
+		{Type: diff.CREATE, Path: []string{"us,db", "size"}, To: 1},
+		{Type: diff.UPDATE, Path: []string{"us,web", "size"}, From: 1, To: 2},
+	}
 
-	cl, err := d.Diff([]int{1, 2, 3}, []int{1, 3, 2})
+	require.Len(t, cl, len(expected))
+	for i, c := range cl {
+		assert.Equal(t, expected[i].Type, c.Type)
+		assert.Equal(t, expected[i].Path, c.Path)
+		assert.Equal(t, expected[i].From, c.From)
+		assert.Equal(t, expected[i].To, c.To)
+	}
+
+	// elements only match when both fields are equal
+	cl, err = diff.Diff([]server{{"eu", "web", 1}}, []server{{"us", "web", 1}})
 	require.Nil(t, err)
+	assert.Len(t, cl.FilterFunc(func(c diff.Change) bool { return c.Type == diff.UPDATE }), 0)
+	assert.Len(t, cl, 6)
+}
 
-	assert.Len(t, cl, 0)
+func TestDiffSyncMap(t *testing.T) {
+	type cache struct {
+		Name    string    `diff:"name"`
+		Entries sync.Map  `diff:"entries"`
+		Shared  *sync.Map `diff:"shared"`
+	}
 
-	d, err = diff.NewDiffer(diff.SliceOrdering(true))
+	a := &cache{Name: "cache", Shared: &sync.Map{}}
+	a.Entries.Store("removed", 1)
+	a.Entries.Store("updated", "old")
+	a.Entries.Store("same", true)
+	a.Shared.Store(1, "one")
+
+	b := &cache{Name: "cache", Shared: &sync.Map{}}
+	b.Entries.Store("added", 2)
+	b.Entries.Store("updated", "new")
+	b.Entries.Store("same", true)
+	b.Shared.Store(1, "uno")
+
+	cl, err := diff.Diff(a, b)
 	require.Nil(t, err)
+	require.Len(t, cl, 4)
 
-	assert.True(t, d.SliceOrdering)
+	changes := map[string]diff.Change{}
+	for _, c := range cl {
+		changes[strings.Join(c.Path, ".")] = c
+	}
 
-	cl, err = d.Diff([]int{1, 2, 3}, []int{1, 3, 2})
+	assert.Equal(t, diff.Change{Type: diff.DELETE, Path: []string{"entries", "removed"}, From: 1}, changes["entries.removed"])
+	assert.Equal(t, diff.Change{Type: diff.CREATE, Path: []string{"entries", "added"}, To: 2}, changes["entries.added"])
+	assert.Equal(t, diff.UPDATE, changes["entries.updated"].Type)
+	assert.Equal(t, "old", changes["entries.updated"].From)
+	assert.Equal(t, "new", changes["entries.updated"].To)
+	assert.Equal(t, diff.UPDATE, changes["shared.1"].Type)
+	assert.Equal(t, "one", changes["shared.1"].From)
+	assert.Equal(t, "uno", changes["shared.1"].To)
+
+	// diffing sync.Maps directly
+	cl, err = diff.Diff(a.Shared, b.Shared)
 	require.Nil(t, err)
+	require.Len(t, cl, 1)
+	assert.Equal(t, []string{"1"}, cl[0].Path)
 
-	assert.Len(t, cl, 2)
+	cl, err = diff.Diff(nil, b.Shared)
+	require.Nil(t, err)
+	require.Len(t, cl, 1)
+	assert.Equal(t, diff.CREATE, cl[0].Type)
+	assert.Equal(t, "uno", cl[0].To)
 
-	// some other options..
+	// sync.Maps held by value can't be read without copying their lock
+	_, err = diff.Diff(cache{Name: "a"}, cache{Name: "b"})
+	assert.Equal(t, diff.ErrUnaddressableSyncMap, err)
 }
 
 func TestDiffPrivateField(t *testing.T) {
@@ -1154,3 +5247,71 @@ func copyAppend(src []string, elems ...string) []string {
 	}
 	return dst
 }
+
+func TestIgnoreTimeFields(t *testing.T) {
+	type audit struct {
+		Name      string     `diff:"name"`
+		CreatedAt time.Time  `diff:"created_at"`
+		UpdatedAt *time.Time `diff:"updated_at"`
+	}
+
+	later := currentTime.Add(time.Hour)
+	a := audit{Name: "one", CreatedAt: currentTime, UpdatedAt: &currentTime}
+	b := audit{Name: "two", CreatedAt: later, UpdatedAt: &later}
+
+	cl, err := diff.Diff(a, b)
+	require.Nil(t, err)
+	assert.Len(t, cl, 3)
+
+	cl, err = diff.Diff(a, b, diff.IgnoreTimeFields(true))
+	require.Nil(t, err)
+	require.Len(t, cl, 1)
+	assert.Equal(t, []string{"name"}, cl[0].Path)
+
+	cl, err = diff.Diff(audit{Name: "one"}, audit{Name: "one", UpdatedAt: &later}, diff.IgnoreTimeFields(true))
+	require.Nil(t, err)
+	assert.Len(t, cl, 0)
+}
+
+func TestPath(t *testing.T) {
+	type key struct {
+		Name string `msgpack:"name"`
+	}
+
+	d, err := diff.NewDiffer(diff.StructMapKeySupport())
+	require.Nil(t, err)
+
+	cl, err := d.Diff(
+		map[string]map[string]int{},
+		map[string]map[string]int{"foo": {"name": 1}},
+	)
+	require.Nil(t, err)
+	require.Len(t, cl, 1)
+
+	p := diff.Path(cl[0].Path)
+	assert.Equal(t, "foo\xa4name", strings.Join(cl[0].Path, ""))
+	assert.Equal(t, "foo.name", p.String())
+
+	cl, err = d.Diff(map[key]int{{"a"}: 1}, map[key]int{{"a"}: 2})
+	require.Nil(t, err)
+	require.Len(t, cl, 1)
+	assert.Equal(t, "map[name:a]", diff.Path(cl[0].Path).String())
+
+	cl, err = d.Diff(map[int]string{5: "a"}, map[int]string{5: "b"})
+	require.Nil(t, err)
+	require.Len(t, cl, 1)
+	assert.Equal(t, "5", diff.Path(cl[0].Path).String())
+
+	p = diff.Path{"nested", "slice", "0"}
+	assert.Equal(t, "nested.slice.0", p.String())
+	assert.True(t, p.Equal(diff.Path{"nested", "slice", "0"}))
+	assert.False(t, p.Equal(diff.Path{"nested", "slice"}))
+	assert.True(t, p.HasPrefix(diff.Path{"nested", "slice"}))
+	assert.True(t, p.HasPrefix(diff.Path{}))
+	assert.False(t, p.HasPrefix(diff.Path{"nested", "map"}))
+	assert.False(t, p.HasPrefix(diff.Path{"nested", "slice", "0", "foo"}))
+
+	var c diff.Change
+	c.Path = p
+	assert.Equal(t, []string{"nested", "slice", "0"}, c.Path)
+}
@@ -11,12 +11,12 @@ import (
 
 func (d *Differ) diffTime(path []string, a, b reflect.Value) error {
 	if a.Kind() == reflect.Invalid {
-		d.cl.Add(CREATE, path, nil, exportInterface(b))
+		d.cl.Add(CREATE, path, nil, timeValue(b))
 		return nil
 	}
 
 	if b.Kind() == reflect.Invalid {
-		d.cl.Add(DELETE, path, exportInterface(a), nil)
+		d.cl.Add(DELETE, path, timeValue(a), nil)
 		return nil
 	}
 
@@ -24,13 +24,27 @@ func (d *Differ) diffTime(path []string, a, b reflect.Value) error {
 		return ErrTypeMismatch
 	}
 
+	at := timeValue(a)
+	bt := timeValue(b)
+
 	// Marshal and unmarshal time type will lose accuracy. Using unix nano to compare time type.
-	au := exportInterface(a).(time.Time).UnixNano()
-	bu := exportInterface(b).(time.Time).UnixNano()
+	// The unix nano of the zero time is undefined, so it is only equal to another zero time
+	changed := at.IsZero() != bt.IsZero()
+	if !at.IsZero() && !bt.IsZero() {
+		changed = at.UnixNano() != bt.UnixNano()
+	}
 
-	if au != bu {
-		d.cl.Add(UPDATE, path, exportInterface(a), exportInterface(b))
+	if changed {
+		d.cl.Add(UPDATE, path, at, bt)
+	} else {
+		d.unchanged(path, a, b, nil)
 	}
 
 	return nil
 }
+
+// timeValue returns the time without its monotonic clock reading, so that
+// stored values compare equal to the same instant parsed or deserialized
+func timeValue(v reflect.Value) time.Time {
+	return exportInterface(v).(time.Time).Round(0)
+}
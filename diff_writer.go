@@ -0,0 +1,69 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// DiffTo diffs a and b and writes the resulting changelog to w as a json array
+func DiffTo(w io.Writer, a, b interface{}, opts ...func(d *Differ) error) error {
+	d, err := NewDiffer(opts...)
+	if err != nil {
+		return err
+	}
+	return d.DiffTo(w, a, b)
+}
+
+// DiffTo diffs a and b and writes the resulting changelog to w as a json array. Changes are
+// streamed to w as they are found, so the changelog is never held in memory as a whole
+func (d *Differ) DiffTo(w io.Writer, a, b interface{}) error {
+	cw := newChangeWriter(w)
+
+	err := d.DiffStream(a, b, cw.write)
+	if err != nil {
+		return err
+	}
+
+	return cw.close()
+}
+
+// changeWriter encodes changes to a json array as they are written
+type changeWriter struct {
+	w     io.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func newChangeWriter(w io.Writer) *changeWriter {
+	return &changeWriter{w: w, enc: json.NewEncoder(w)}
+}
+
+func (cw *changeWriter) write(c Change) error {
+	sep := ","
+	if cw.count == 0 {
+		sep = "["
+	}
+
+	_, err := io.WriteString(cw.w, sep)
+	if err != nil {
+		return err
+	}
+
+	cw.count++
+
+	return cw.enc.Encode(c)
+}
+
+func (cw *changeWriter) close() error {
+	if cw.count == 0 {
+		_, err := io.WriteString(cw.w, "[]\n")
+		return err
+	}
+
+	_, err := io.WriteString(cw.w, "]\n")
+	return err
+}
@@ -9,6 +9,8 @@ var (
 	ErrTypeMismatch = NewError("types do not match")
 	// ErrInvalidChangeType The specified change values are not unsupported
 	ErrInvalidChangeType = NewError("change type must be one of 'create' or 'delete'")
+	// ErrUnaddressableSyncMap A sync.Map can't be read without being copied, along with its lock
+	ErrUnaddressableSyncMap = NewError("sync.Map must be diffed through a pointer or an addressable value")
 )
 
 //our own version of an error, which can wrap others
@@ -6,6 +6,22 @@ package diff
 
 import "regexp"
 
+// onlyPath returns true if the path leads to one of the paths, or lies below it
+func onlyPath(paths [][]string, path []string) bool {
+	for _, p := range paths {
+		n := len(path)
+		if n > len(p) {
+			n = len(p)
+		}
+
+		if pathmatch(p[:n], path) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func pathmatch(filter, path []string) bool {
 	for i, f := range filter {
 		if len(path) < i+1 {
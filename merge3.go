@@ -0,0 +1,172 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Conflict describes a path that was changed differently by both sides of a three-way merge
+type Conflict struct {
+	Path    []string
+	BaseVal interface{}
+	AVal    interface{}
+	BVal    interface{}
+}
+
+// Merge3 performs a three-way merge. The changes from base to a and from base to b are applied to
+// target, except where both sides changed the same path to different values, or where one side
+// changed a value that the other side changed within. Those changes are left out and returned as
+// conflicts, reported at the outermost path involved. Identical changes on both sides are applied once
+func Merge3(base, a, b, target interface{}) (PatchLog, []Conflict, error) {
+	d, err := NewDiffer()
+	if err != nil {
+		return nil, nil, err
+	}
+	return d.Merge3(base, a, b, target)
+}
+
+// Merge3 performs a three-way merge of a and b, using base as their common ancestor. The differ
+// itself is left unchanged
+func (d *Differ) Merge3(base, a, b, target interface{}) (PatchLog, []Conflict, error) {
+	// map keys must be encoded so they can be patched, which is only enabled for this merge
+	md := *d
+	if err := StructMapKeySupport()(&md); err != nil {
+		return nil, nil, err
+	}
+
+	return md.merge3(base, a, b, target)
+}
+
+func (d *Differ) merge3(base, a, b, target interface{}) (PatchLog, []Conflict, error) {
+	acl, err := d.Diff(base, a)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bcl, err := d.Diff(base, b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	achanges, aouter := mergeIndex(acl)
+	bchanges, bouter := mergeIndex(bcl)
+
+	var conflicts []Conflict
+	conflicted := make(map[string]bool)
+	applied := make(map[string]bool)
+
+	for _, ac := range acl {
+		k := mergeKey(ac.Path)
+
+		if bc, ok := bchanges[k]; ok {
+			if ac.Type == bc.Type && reflect.DeepEqual(ac.To, bc.To) {
+				applied[k] = true
+				continue
+			}
+
+			conflicted[k] = true
+			conflicts = append(conflicts, Conflict{
+				Path:    ac.Path,
+				BaseVal: ac.From,
+				AVal:    ac.To,
+				BVal:    bc.To,
+			})
+			continue
+		}
+
+		// b changed values held by the value a changed
+		if bouter[k] {
+			conflicted[k] = true
+			conflicts = append(conflicts, Conflict{
+				Path:    ac.Path,
+				BaseVal: ac.From,
+				AVal:    ac.To,
+				BVal:    d.mergedValue(ac.From, ac.Path, bcl),
+			})
+		}
+	}
+
+	// a changed values held by the value b changed
+	for _, bc := range bcl {
+		k := mergeKey(bc.Path)
+		if _, ok := achanges[k]; !ok && aouter[k] {
+			conflicted[k] = true
+			conflicts = append(conflicts, Conflict{
+				Path:    bc.Path,
+				BaseVal: bc.From,
+				AVal:    d.mergedValue(bc.From, bc.Path, acl),
+				BVal:    bc.To,
+			})
+		}
+	}
+
+	var cl Changelog
+
+	for _, ac := range acl {
+		if !withinConflict(ac.Path, conflicted) {
+			cl = append(cl, ac)
+		}
+	}
+
+	for _, bc := range bcl {
+		if !applied[mergeKey(bc.Path)] && !withinConflict(bc.Path, conflicted) {
+			cl = append(cl, bc)
+		}
+	}
+
+	return d.Patch(cl, target), conflicts, nil
+}
+
+// mergeIndex indexes the changes by path, along with every path that holds a changed value
+func mergeIndex(cl Changelog) (changes map[string]Change, outer map[string]bool) {
+	changes = make(map[string]Change, len(cl))
+	outer = make(map[string]bool)
+
+	for _, c := range cl {
+		changes[mergeKey(c.Path)] = c
+		for i := 0; i < len(c.Path); i++ {
+			outer[mergeKey(c.Path[:i])] = true
+		}
+	}
+
+	return changes, outer
+}
+
+// withinConflict returns true if the path, or any path holding it, is conflicted
+func withinConflict(path []string, conflicted map[string]bool) bool {
+	for i := 0; i <= len(path); i++ {
+		if conflicted[mergeKey(path[:i])] {
+			return true
+		}
+	}
+	return false
+}
+
+// mergedValue returns the value at path once the changes made within it are applied to base
+func (d *Differ) mergedValue(base interface{}, path []string, cl Changelog) interface{} {
+	if base == nil {
+		return nil
+	}
+
+	var ncl Changelog
+	for _, c := range cl {
+		if len(c.Path) > len(path) && Path(c.Path).HasPrefix(path) {
+			ncl = append(ncl, c)
+		}
+	}
+
+	v := reflect.New(reflect.TypeOf(base))
+	v.Elem().Set(deepCopy(reflect.ValueOf(base)))
+
+	d.Patch(ncl.RewritePaths(path, nil), v.Interface())
+
+	return v.Elem().Interface()
+}
+
+func mergeKey(path []string) string {
+	return strings.Join(path, "\x00")
+}
@@ -1,6 +1,12 @@
 package diff
 
-// ConvertTypes enables values that are convertible to the target type to be converted when patching
+import (
+	"errors"
+	"reflect"
+)
+
+// ConvertTypes enables values that are convertible to the target type to be converted when patching.
+// Structs of a different type are converted field by field, matching fields by their tag name
 func ConvertCompatibleTypes() func(d *Differ) error {
 	return func(d *Differ) error {
 		d.ConvertCompatibleTypes = true
@@ -24,6 +30,418 @@ func SliceOrdering(enabled bool) func(d *Differ) error {
 	}
 }
 
+// ReorderAsWhole reports a slice that contains the same elements in a different order as a single
+// update of the whole slice, rather than an update per index. Only applies when SliceOrdering is enabled
+func ReorderAsWhole(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.ReorderAsWhole = enabled
+		return nil
+	}
+}
+
+// IncludeUnchanged reports values that have not changed as an EQUAL change,
+// so the changelog contains every compared value, not only the differences
+func IncludeUnchanged(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.IncludeUnchanged = enabled
+		return nil
+	}
+}
+
+// HexEncodeBytes stores byte slices in the changelog as hex encoded strings.
+// Patch decodes the strings back to bytes when applying the changelog
+func HexEncodeBytes(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.HexEncodeBytes = enabled
+		return nil
+	}
+}
+
+// MapDefault sets a function providing default values for keys that are missing from a map
+func MapDefault(f MapDefaultFunc) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.MapDefault = f
+		return nil
+	}
+}
+
+// UntaggedStructsAtomic compares structs that have no fields tagged with the differ's tag name as a single
+// value, reporting one change for the whole struct rather than descending into its fields
+func UntaggedStructsAtomic(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.UntaggedStructsAtomic = enabled
+		return nil
+	}
+}
+
+// SliceLCS compares ordered slices by their longest common subsequence, so that inserting or removing
+// an element reports a single change rather than an update for every following index. Only applies
+// when SliceOrdering is enabled. Changelogs produced with this option must be applied by a differ
+// that also has it enabled, so that creates and deletes shift the following elements. Large slices
+// whose differing elements are too many to compare pairwise are compared by index instead
+func SliceLCS(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.SliceLCS = enabled
+		return nil
+	}
+}
+
+// CompareFuncByPointer compares functions by their code pointer, reporting an update when a function
+// is set, removed or replaced by a different function. Closures created by the same function literal
+// share a code pointer, so are considered equal. Patching a function is a no-op
+func CompareFuncByPointer(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.CompareFuncByPointer = enabled
+		return nil
+	}
+}
+
+// MapValueEqual compares the values of keys present in both maps using a custom predicate, rather than
+// recursively diffing them. A single update of the whole value is reported when the predicate returns false
+func MapValueEqual(f func(a, b interface{}) bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.MapValueEqual = f
+		return nil
+	}
+}
+
+// OmitEmpty treats empty values the way encoding/json's omitempty does, so that a value omitted from
+// a marshaled document is not reported as deleted. A map key missing on one side is considered equal to
+// an empty value on the other, as are empty values of struct fields tagged with omitempty
+func OmitEmpty(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.OmitEmpty = enabled
+		return nil
+	}
+}
+
+// IgnorePaths skips diffing the given paths and everything below them. Path elements may
+// contain valid regexp, the same as Changelog.Filter
+func IgnorePaths(paths ...[]string) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.IgnorePaths = append(d.IgnorePaths, paths...)
+		return nil
+	}
+}
+
+// OnlyFields only diffs the given paths, along with everything below them. All other paths are
+// skipped without being visited. Path elements may contain valid regexp, the same as IgnorePaths
+func OnlyFields(paths ...[]string) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.OnlyPaths = append(d.OnlyPaths, paths...)
+		return nil
+	}
+}
+
+// AtomicTypes compares values of the given types as a whole using reflect.DeepEqual, reporting a single
+// update if they differ, rather than descending into them
+func AtomicTypes(types ...reflect.Type) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.AtomicTypes = append(d.AtomicTypes, types...)
+		return nil
+	}
+}
+
+// TrackComparativeIndices records the old and new positions of identified slice elements on
+// the changes made to them, which is useful when elements have both moved and changed. The
+// changes can then be grouped by element with Changelog.NestByComparative
+func TrackComparativeIndices(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.TrackComparativeIndices = enabled
+		return nil
+	}
+}
+
+// StringSynonyms treats strings within the same group as equal, such as "yes", "true" and "1".
+// Strings that are not part of any group are compared as usual
+func StringSynonyms(groups [][]string) func(d *Differ) error {
+	return func(d *Differ) error {
+		if d.stringSynonyms == nil {
+			d.stringSynonyms = make(map[string]int)
+		}
+
+		// keep groups from earlier calls apart
+		offset := 0
+		for _, g := range d.stringSynonyms {
+			if g >= offset {
+				offset = g + 1
+			}
+		}
+
+		for i, group := range groups {
+			for _, s := range group {
+				d.stringSynonyms[s] = offset + i
+			}
+		}
+
+		return nil
+	}
+}
+
+// ChunkedSlices compares slices of basic types, such as large byte buffers, in blocks of the given
+// number of elements. Each run of differing elements is reported as a single change, located at the
+// offset of the run and holding sub-slices of the differing elements. These changes are intended for
+// reporting, they cannot be applied with Patch
+func ChunkedSlices(blockSize int) func(d *Differ) error {
+	return func(d *Differ) error {
+		if blockSize <= 0 {
+			return errors.New("block size must be greater than zero")
+		}
+		d.ChunkSize = blockSize
+		return nil
+	}
+}
+
+// MaxValueBytes replaces From and To values whose msgpack encoding is larger than n bytes
+// with a TruncatedValue, recording only their encoded size. Patch ignores changes that would
+// set a truncated value
+func MaxValueBytes(n int) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.MaxValueBytes = n
+		return nil
+	}
+}
+
+// WarnOnIdentifierChange reports a warning when an identified slice element appears to have had its
+// identifier changed. An element is assumed to be the same when it is found at the same position in
+// both slices, and neither identifier is present in the other slice
+func WarnOnIdentifierChange(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.WarnOnIdentifierChange = enabled
+		return nil
+	}
+}
+
+// SemanticJSON compares json.RawMessage values, and byte slice fields tagged with the json option,
+// by decoding them and diffing the decoded values. Changes are reported at paths below the field.
+// Documents that are not valid json are compared byte for byte, and a warning is reported
+func SemanticJSON(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.SemanticJSON = enabled
+		return nil
+	}
+}
+
+// MapValueKey pairs up the entries of maps by a key derived from their values, rather than by their map
+// keys. This suits maps keyed by opaque ids, whose values carry a stable natural key. Changes are reported
+// at the entries' map keys. An entry that moved to a different map key is reported as the deletion of the
+// old key and the creation of the new key with its previous value, followed by only the changes to its
+// value. Keys are all vacated before any are created, so the changelog can still be patched, even when
+// entries swapped keys. The derived keys must be comparable and unique within a map, otherwise diffing fails
+func MapValueKey(f func(value interface{}) interface{}) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.MapValueKey = f
+		return nil
+	}
+}
+
+// TagFallback sets the tags to consult, in order, for the name of a field that isn't named by the
+// primary tag, before defaulting to the field's name. Tag options are only read from the primary tag
+func TagFallback(tags ...string) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.TagFallback = tags
+		return nil
+	}
+}
+
+// StructWholeThreshold reports a struct as a single update of the whole value when the fraction
+// of its fields that have changed is greater than the threshold
+func StructWholeThreshold(fraction float64) func(d *Differ) error {
+	return func(d *Differ) error {
+		if fraction < 0 || fraction > 1 {
+			return errors.New("struct whole threshold must be between 0 and 1")
+		}
+		d.StructWholeThreshold = fraction
+		return nil
+	}
+}
+
+// NumericEquality treats numbers of different types as equal when they hold the same value,
+// such as int(3) and float64(3)
+func NumericEquality(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.NumericEquality = enabled
+		return nil
+	}
+}
+
+// TrustHash skips diffing values that implement Hasher when their hashes are equal. This is only
+// safe if the hash changes whenever the value does, otherwise changes will be missed
+func TrustHash(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.TrustHash = enabled
+		return nil
+	}
+}
+
+// ValueFormatter renders the From and To values of each change with f, storing the result in
+// FromFormatted and ToFormatted. Nil values are not formatted
+func ValueFormatter(f func(v interface{}) string) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.ValueFormatter = f
+		return nil
+	}
+}
+
+// AttachKinds records the reflect.Kind names of the From and To values of each change in FromKind
+// and ToKind. Nil values have no kind
+func AttachKinds(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.AttachKinds = enabled
+		return nil
+	}
+}
+
+// TypedValues records the names that the types of the From and To values of each change were
+// registered under with RegisterType in FromType and ToType. The names are kept when marshaling to
+// json, so values of registered types are rebuilt with their concrete type when unmarshaling
+func TypedValues(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.TypedValues = enabled
+		return nil
+	}
+}
+
+// DereferencePointers compares a pointer with a value of the type it points to by the value it
+// points to, rather than reporting a type mismatch. Changes hold the dereferenced values
+func DereferencePointers(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.DereferencePointers = enabled
+		return nil
+	}
+}
+
+// IgnoreUnsupported skips values of kinds that can't be diffed, such as functions, rather than
+// returning an error. Channels are always skipped
+func IgnoreUnsupported(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.IgnoreUnsupported = enabled
+		return nil
+	}
+}
+
+// FuzzySliceMatch pairs up numeric elements of unordered slices that differ by no more than the
+// threshold, reporting an update of the element rather than its deletion and the creation of
+// another. Each element is paired with the nearest element that hasn't been paired yet
+func FuzzySliceMatch(threshold float64) func(d *Differ) error {
+	return func(d *Differ) error {
+		if threshold < 0 {
+			return errors.New("fuzzy slice threshold must not be negative")
+		}
+		d.FuzzySliceThreshold = threshold
+		return nil
+	}
+}
+
+// NilEqualsZeroStruct treats a nil pointer to a struct as equal to the zero value of the struct,
+// either held directly or through a pointer
+func NilEqualsZeroStruct(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.NilEqualsZeroStruct = enabled
+		return nil
+	}
+}
+
+// OmitZeroCreates leaves out the creation of struct fields that hold the zero value of their type
+// when a struct is created as a whole, including the fields of nested structs and those reported by
+// IncludeUnchanged. Unlike DisableStructValues, fields with a value are kept
+func OmitZeroCreates(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.OmitZeroCreates = enabled
+		return nil
+	}
+}
+
+// EmitEndMarker passes a final END change to the emit func of DiffStream once the diff has
+// completed, holding a StreamSummary of the changes that were emitted
+func EmitEndMarker(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.EmitEndMarker = enabled
+		return nil
+	}
+}
+
+// IncludeUnexported diffs every unexported field, including the fields of structs, pointers and
+// maps held by one, and reports their values with their own type. Unexported values are read
+// through unsafe, bypassing the protection of the reflect package, so the values in the changelog
+// must not be modified. Patch does not apply changes to unexported fields
+func IncludeUnexported(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.IncludeUnexported = enabled
+		return nil
+	}
+}
+
+// PositionalStructSlices compares the elements of equal length slices of structs without identifiers
+// by their position, reporting changes to the fields of each element rather than matching elements
+// by their values
+func PositionalStructSlices(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.PositionalStructSlices = enabled
+		return nil
+	}
+}
+
+// OrderedMap registers a type that holds an ordered map, such as a slice of key and value pairs.
+// Values of the type are compared by key like a map, using f to list their entries. A change to
+// the order of the entries present in both values is reported as an update of the list of keys.
+// Changes are located by key rather than by position, so they can't be applied with Patch, which
+// fails on each of them and leaves the ordered map untouched
+func OrderedMap(t reflect.Type, f OrderedMapFunc) func(d *Differ) error {
+	return func(d *Differ) error {
+		if d.orderedMaps == nil {
+			d.orderedMaps = make(map[reflect.Type]OrderedMapFunc)
+		}
+		d.orderedMaps[t] = f
+		return nil
+	}
+}
+
+// EqualityMethod compares values of types that have a method with the given name, taking a value
+// of the same type and returning a bool, by calling it rather than comparing their contents
+func EqualityMethod(name string) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.EqualityMethod = name
+		return nil
+	}
+}
+
+// ReversePerspective produces changelogs that turn b into a, by diffing the values the other way
+// around. Unlike reversing a changelog, slice changes are found from the perspective of b
+func ReversePerspective(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.ReversePerspective = enabled
+		return nil
+	}
+}
+
+// FlattenMaps compares nested map[string]interface{} values as a single map, with the keys of
+// nested maps joined by the separator, such as "a.b.c". Patching with the same option set expands
+// flattened keys back into nested maps, creating them where needed. Nested maps emptied by a patch
+// are kept. Diffing fails on keys that hold the separator, such as "a.b", as they would be patched
+// back as nested maps
+func FlattenMaps(separator string) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.MapSeparator = separator
+		return nil
+	}
+}
+
+// IgnoreByTag skips struct fields whose tag key is set to value, such as fields tagged audit:"false".
+// It is combined with any Filter set before it
+func IgnoreByTag(key, value string) func(d *Differ) error {
+	return func(d *Differ) error {
+		filter := d.Filter
+		d.Filter = func(path []string, parent reflect.Type, field reflect.StructField) bool {
+			if v, ok := field.Tag.Lookup(key); ok && v == value {
+				return false
+			}
+			return filter == nil || filter(path, parent, field)
+		}
+		return nil
+	}
+}
+
 // TagName sets the tag name to use when getting field names and options
 func TagName(tag string) func(d *Differ) error {
 	return func(d *Differ) error {
@@ -52,7 +470,44 @@ func CustomValueDiffers(vd ...ValueDiffer) func(d *Differ) error {
 	}
 }
 
-// AllowTypeMismatch changed behaviour to report value as "updated" when its type has changed instead of error
+// RegisterNamedDiffer registers a custom differ under name. Struct fields tagged with the differ
+// option, such as `diff:"amount,differ=money"`, are compared with the named differ instead of
+// any differ matching their type
+func RegisterNamedDiffer(name string, vd ValueDiffer) func(d *Differ) error {
+	return func(d *Differ) error {
+		if d.namedDiffers == nil {
+			d.namedDiffers = make(map[string]ValueDiffer)
+		}
+		vd.InsertParentDiffer(d.diff)
+		d.namedDiffers[name] = vd
+		return nil
+	}
+}
+
+// RegisterComparator registers an equality function under name. Struct fields tagged with the
+// comparator option, such as `diff:"score,comparator=approx"`, are compared as a whole with it
+func RegisterComparator(name string, f func(a, b interface{}) bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		if d.comparators == nil {
+			d.comparators = make(map[string]func(a, b interface{}) bool)
+		}
+		d.comparators[name] = f
+		return nil
+	}
+}
+
+// ComputedField registers a value derived from structs, such as the result of one of their methods,
+// that is diffed along with their fields under name. fn is called with every struct being compared,
+// and should return nil for structs it doesn't apply to
+func ComputedField(name string, fn func(interface{}) interface{}) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.computedFields = append(d.computedFields, computedField{name, fn})
+		return nil
+	}
+}
+
+// AllowTypeMismatch changed behaviour to report value as "updated" when its type has changed instead of error.
+// Interfaces holding values of different types are always reported as updated
 func AllowTypeMismatch(enabled bool) func(d *Differ) error {
 	return func(d *Differ) error {
 		d.AllowTypeMismatch = enabled
@@ -91,3 +546,11 @@ func Filter(f FilterFunc) func(d *Differ) error {
 		return nil
 	}
 }
+
+// IgnoreTimeFields skips any time.Time or *time.Time values when diffing
+func IgnoreTimeFields(enabled bool) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.IgnoreTimeFields = enabled
+		return nil
+	}
+}
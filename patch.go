@@ -1,6 +1,7 @@
 package diff
 
 import (
+	"encoding/hex"
 	"reflect"
 )
 
@@ -133,11 +134,120 @@ func Patch(cl Changelog, target interface{}) (ret PatchLog) {
 //Patch... the missing feature.
 func (d *Differ) Patch(cl Changelog, target interface{}) (ret PatchLog) {
 	for _, c := range cl {
-		ret = append(ret, NewPatchLogEntry(NewChangeValue(d, c, target)))
+		ret = append(ret, d.ApplyChange(c, target))
 	}
 	return ret
 }
 
+// ApplyChange applies a single change to target
+func ApplyChange(c Change, target interface{}) PatchLogEntry {
+	d, _ := NewDiffer()
+	return d.ApplyChange(c, target)
+}
+
+// ApplyChange applies a single change to target, as a one element Patch would
+func (d *Differ) ApplyChange(c Change, target interface{}) PatchLogEntry {
+	return NewPatchLogEntry(NewChangeValue(d, c, target))
+}
+
+// Unpatch reverts the changes of the changelog on target
+func Unpatch(cl Changelog, target interface{}) PatchLog {
+	d, _ := NewDiffer()
+	return d.Unpatch(cl, target)
+}
+
+// Unpatch applies the reverse of the changelog to target, undoing a previous Patch
+func (d *Differ) Unpatch(cl Changelog, target interface{}) PatchLog {
+	return d.Patch(cl.Reverse(), target)
+}
+
+// PatchNoDelete applies the changes of the changelog to target, skipping all deletions
+func PatchNoDelete(cl Changelog, target interface{}) PatchLog {
+	d, _ := NewDiffer()
+	return d.PatchNoDelete(cl, target)
+}
+
+// PatchNoDelete applies only the creations and updates of the changelog to target, leaving values
+// that were deleted in place. Skipped deletions are left out of the patch log
+func (d *Differ) PatchNoDelete(cl Changelog, target interface{}) PatchLog {
+	return d.Patch(cl.FilterFunc(func(c Change) bool {
+		return c.Type != DELETE
+	}), target)
+}
+
+// PatchStrict applies changes in order, stopping at the first change that fails
+func PatchStrict(cl Changelog, target interface{}) (PatchLog, error) {
+	d, _ := NewDiffer()
+	return d.PatchStrict(cl, target)
+}
+
+// PatchStrict applies changes in order like Patch, but stops at the first change that fails. The
+// changes applied so far, including the failed one, are returned along with its error
+func (d *Differ) PatchStrict(cl Changelog, target interface{}) (PatchLog, error) {
+	var ret PatchLog
+	for _, c := range cl {
+		ple := NewPatchLogEntry(NewChangeValue(d, c, target))
+		ret = append(ret, ple)
+
+		if ple.Errors != nil {
+			return ret, ple.Errors
+		}
+	}
+	return ret, nil
+}
+
+//patchValue returns the value to set from the change. Byte slices and arrays
+//stored as hex strings are decoded back to bytes when HexEncodeBytes is enabled,
+//and ip addresses and networks are parsed from their textual form
+func (d *Differ) patchValue(c *ChangeValue) reflect.Value {
+	value := reflect.ValueOf(c.change.To)
+
+	if d.ConvertCompatibleTypes && value.IsValid() {
+		value = d.convertStruct(value, c.target.Type())
+	}
+
+	s, ok := c.change.To.(string)
+	if ok {
+		if ip, isIP, err := parseIP(s, c.target.Type()); isIP {
+			if err != nil {
+				c.AddError(NewError("Unable to parse ip value", err))
+				return value
+			}
+			return ip
+		}
+	}
+
+	if !d.HexEncodeBytes || !ok {
+		return value
+	}
+
+	t := c.target.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if (t.Kind() != reflect.Slice && t.Kind() != reflect.Array) || t.Elem().Kind() != reflect.Uint8 {
+		return value
+	}
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		c.AddError(NewError("Unable to decode hex value", err))
+		return value
+	}
+
+	if t.Kind() == reflect.Array {
+		if len(b) != t.Len() {
+			c.AddError(NewErrorf("Unable to decode hex value of %d bytes to %s", len(b), t))
+			return value
+		}
+		a := reflect.New(t).Elem()
+		reflect.Copy(a, reflect.ValueOf(b))
+		return a
+	}
+
+	return reflect.ValueOf(b).Convert(t)
+}
+
 //NewPatchLogEntry converts our complicated reflection based struct to
 //a simpler format for the consumer
 func NewPatchLogEntry(cv *ChangeValue) PatchLogEntry {
@@ -157,6 +267,14 @@ func NewChangeValue(d *Differ, c Change, target interface{}) (ret *ChangeValue)
 		target: &val,
 		change: &c,
 	}
+
+	// warnings only inform about the diff, and redacted or truncated changes no longer hold the
+	// values to apply
+	if c.Type == WARNING || c.Type == END || c.isRedacted() || c.isTruncated() {
+		ret.SetFlag(FlagIgnored)
+		return
+	}
+
 	d.renderChangeTarget(ret)
 	return
 }
@@ -184,7 +302,9 @@ func (d *Differ) renderChangeTarget(c *ChangeValue) {
 
 	//path element that is a slice
 	case reflect.Slice:
-		d.renderSlice(c)
+		if !d.renderSlice(c) {
+			return
+		}
 
 	//walking a path means dealing with real elements
 	case reflect.Interface, reflect.Ptr:
@@ -202,7 +322,9 @@ func (d *Differ) renderChangeTarget(c *ChangeValue) {
 
 	//path element that is a struct
 	case reflect.Struct:
-		d.patchStruct(c)
+		if !d.patchStruct(c) {
+			return
+		}
 	}
 
 	//if for some reason, rendering this element fails, c will no longer be valid
@@ -227,10 +349,18 @@ func (d *Differ) renderChangeTarget(c *ChangeValue) {
 			default:
 				c.SetFlag(FlagIgnored)
 			}
+		case EQUAL:
+			c.SetFlag(FlagIgnored)
 		case UPDATE, CREATE:
+			// functions are only compared, never patched
+			if c.target.Kind() == reflect.Func {
+				c.SetFlag(FlagIgnored)
+				return
+			}
+
 			// this is generic because... we only deal in primitives here. AND
 			// the diff format To field already contains the correct type.
-			c.Set(reflect.ValueOf(c.change.To), d.ConvertCompatibleTypes)
+			c.Set(d.patchValue(c), d.ConvertCompatibleTypes)
 			c.SetFlag(FlagUpdated)
 		}
 	}
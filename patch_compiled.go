@@ -0,0 +1,180 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import "reflect"
+
+// CompiledPatch is a changelog resolved against a struct type, so that it can be applied to
+// many values of that type without looking up fields by name for every change
+type CompiledPatch struct {
+	d       *Differ
+	t       reflect.Type
+	cl      Changelog
+	indices [][]int
+}
+
+// CompilePatch resolves the changelog against the type of sample
+func CompilePatch(cl Changelog, sample interface{}) CompiledPatch {
+	d, _ := NewDiffer()
+	return d.CompilePatch(cl, sample)
+}
+
+// CompilePatch resolves the path of each change in the changelog to the index of a struct field
+// on the type of sample, which may be a struct or a pointer to one. Changes that can't be resolved
+// to a plain field, such as those into maps, slices or pointers, are applied with Patch instead
+func (d *Differ) CompilePatch(cl Changelog, sample interface{}) CompiledPatch {
+	t := reflect.TypeOf(sample)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	p := CompiledPatch{d: d, t: t, cl: cl, indices: make([][]int, len(cl))}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return p
+	}
+
+	for i, c := range cl {
+		p.indices[i] = d.compileFields(t, c.Path)
+	}
+
+	return p
+}
+
+// Patch applies the compiled changelog to target, which must be a pointer to the compiled type.
+// Any other target is patched with the changelog as usual
+func (p CompiledPatch) Patch(target interface{}) (ret PatchLog) {
+	v := reflect.ValueOf(target)
+	if p.t == nil || v.Kind() != reflect.Ptr || v.IsNil() || v.Type().Elem() != p.t {
+		return p.d.Patch(p.cl, target)
+	}
+
+	v = v.Elem()
+
+	for i, c := range p.cl {
+		if p.indices[i] == nil || c.isRedacted() || c.isTruncated() || (c.Type != UPDATE && c.Type != CREATE && c.Type != DELETE) {
+			ret = append(ret, NewPatchLogEntry(NewChangeValue(p.d, c, target)))
+			continue
+		}
+
+		ret = append(ret, NewPatchLogEntry(p.d.patchField(v, p.indices[i], c)))
+	}
+
+	return ret
+}
+
+// patchField sets the field at the index on the struct, as renderChangeTarget would
+// once it has walked the change's path
+func (d *Differ) patchField(v reflect.Value, index []int, c Change) *ChangeValue {
+	parent := v
+	for _, i := range index[:len(index)-1] {
+		parent = parent.Field(i)
+	}
+	target := parent.Field(index[len(index)-1])
+
+	cv := &ChangeValue{
+		parent: &parent,
+		target: &target,
+		change: &c,
+		pos:    len(c.Path),
+	}
+
+	if c.Type == DELETE {
+		d.deleteStructEntry(cv)
+		return cv
+	}
+
+	cv.Set(d.patchValue(cv), d.ConvertCompatibleTypes)
+	cv.SetFlag(FlagUpdated)
+
+	return cv
+}
+
+// compileFields returns the index of the field at the path. Only paths made up of exported
+// struct fields, without any patch options, that end in a value of a basic kind are resolved
+func (d *Differ) compileFields(t reflect.Type, path []string) []int {
+	if len(path) == 0 {
+		return nil
+	}
+
+	var index []int
+
+	for _, name := range path {
+		if t.Kind() != reflect.Struct {
+			return nil
+		}
+
+		f, ok := d.compileField(t, name)
+		if !ok {
+			return nil
+		}
+
+		index = append(index, f.Index...)
+		t = f.Type
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Array, reflect.Struct, reflect.Func, reflect.Chan:
+		return nil
+	}
+
+	return index
+}
+
+// pathField finds the field named by a path segment, as patchStruct would
+func (d *Differ) pathField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for _, f := range nestedFieldTypes(t, nil, d.FlattenEmbeddedStructs) {
+		tname := tagName(d.TagName, f, d.TagFallback...)
+		if tname == "-" {
+			continue
+		}
+
+		if tname == name || f.Name == name {
+			return f, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// compileField finds the field matching name in the same way as patchStruct
+func (d *Differ) compileField(t reflect.Type, name string) (reflect.StructField, bool) {
+	f, ok := d.pathField(t, name)
+	if !ok {
+		return f, false
+	}
+
+	if f.PkgPath != "" ||
+		hasTagOption(d.TagName, f, "nocreate") ||
+		hasTagOption(d.TagName, f, "omitunequal") ||
+		hasTagOption(d.TagName, f, "immutable") {
+		return f, false
+	}
+
+	return f, true
+}
+
+// nestedFieldTypes mirrors getNestedFields for a type, setting the index of each field
+// relative to the outermost struct
+func nestedFieldTypes(t reflect.Type, prefix []int, flattenEmbedded bool) []reflect.StructField {
+	fields := make([]reflect.StructField, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		f.Index = append(copyIndex(prefix), i)
+
+		if f.Type.Kind() == reflect.Struct && f.Anonymous && flattenEmbedded {
+			fields = append(fields, nestedFieldTypes(f.Type, f.Index, flattenEmbedded)...)
+		} else {
+			fields = append(fields, f)
+		}
+	}
+
+	return fields
+}
+
+func copyIndex(index []int) []int {
+	return append(make([]int, 0, len(index)+1), index...)
+}
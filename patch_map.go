@@ -1,28 +1,30 @@
 package diff
 
 import (
+	"bytes"
 	"errors"
 	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/vmihailenco/msgpack/v5"
 )
 
 // renderMap - handle map rendering for patch
 func (d *Differ) renderMap(c *ChangeValue) (m, k, v *reflect.Value) {
-	//we must tease out the type of the key, we use the msgpack from diff to recreate the key
-	kt := c.target.Type().Key()
-	field := reflect.New(kt)
+	field := c.change.Path[c.pos]
+	if d.MapSeparator != "" && c.target.Type() == flatMapType {
+		field = d.unflattenTarget(c, field)
+	}
 
-	if d.StructMapKeys {
-		if err := msgpack.Unmarshal([]byte(c.change.Path[c.pos]), field.Interface()); err != nil {
-			c.SetFlag(FlagIgnored)
-			c.AddError(NewError("Unable to unmarshal path element to target type for key in map", err))
-			return
-		}
-		c.key = field.Elem()
-	} else {
-		c.key = reflect.ValueOf(c.change.Path[c.pos])
+	//we must tease out the type of the key, we use the msgpack from diff to recreate the key
+	mk, err := d.mapKey(field, c.target.Type().Key())
+	if err != nil {
+		c.SetFlag(FlagIgnored)
+		c.AddError(NewError("Unable to unmarshal path element to target type for key in map", err))
+		return
 	}
+	c.key = mk
 
 	if c.target.IsNil() && c.target.IsValid() {
 		c.target.Set(reflect.MakeMap(c.target.Type()))
@@ -67,6 +69,95 @@ func (d *Differ) renderMap(c *ChangeValue) (m, k, v *reflect.Value) {
 
 }
 
+// unflattenTarget - moves the target down through the nested maps named by a flattened key,
+// creating them where they are missing, and returns the key within the innermost map
+func (d *Differ) unflattenTarget(c *ChangeValue, field string) string {
+	parts := strings.Split(field, d.MapSeparator)
+
+	if c.target.IsNil() {
+		c.target.Set(reflect.MakeMap(c.target.Type()))
+	}
+
+	m := c.target.Interface().(map[string]interface{})
+	for _, p := range parts[:len(parts)-1] {
+		nm, ok := m[p].(map[string]interface{})
+		if !ok || nm == nil {
+			if c.change.Type == DELETE {
+				return field
+			}
+			nm = make(map[string]interface{})
+			m[p] = nm
+		}
+		m = nm
+	}
+
+	t := reflect.ValueOf(m)
+	c.target = &t
+
+	return parts[len(parts)-1]
+}
+
+// mapKey - converts a path element back to a key of the map's key type. Basic
+// types are parsed from their textual form, while complex keys are expected to
+// be msgpack encoded, as produced by diff with StructMapKeySupport enabled
+func (d *Differ) mapKey(field string, kt reflect.Type) (reflect.Value, error) {
+	key := reflect.New(kt)
+
+	switch kt.Kind() {
+	case reflect.String:
+		if d.StructMapKeys && unmarshalKey(field, key) == nil {
+			return key.Elem(), nil
+		}
+		return reflect.ValueOf(field).Convert(kt), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(field, 10, kt.Bits()); err == nil {
+			key.Elem().SetInt(n)
+			return key.Elem(), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseUint(field, 10, kt.Bits()); err == nil {
+			key.Elem().SetUint(n)
+			return key.Elem(), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, err := strconv.ParseFloat(field, kt.Bits()); err == nil {
+			key.Elem().SetFloat(n)
+			return key.Elem(), nil
+		}
+	case reflect.Bool:
+		if n, err := strconv.ParseBool(field); err == nil {
+			key.Elem().SetBool(n)
+			return key.Elem(), nil
+		}
+	case reflect.Interface:
+		if !d.StructMapKeys || unmarshalKey(field, key) != nil {
+			return reflect.ValueOf(field), nil
+		}
+		return key.Elem(), nil
+	}
+
+	if err := unmarshalKey(field, key); err != nil {
+		return reflect.Value{}, err
+	}
+
+	return key.Elem(), nil
+}
+
+// unmarshalKey - decodes a msgpack encoded key, making sure the entire
+// path element was consumed
+func unmarshalKey(field string, key reflect.Value) error {
+	r := bytes.NewReader([]byte(field))
+	if err := msgpack.NewDecoder(r).Decode(key.Interface()); err != nil {
+		return err
+	}
+
+	if r.Len() > 0 {
+		return errors.New("path element contains trailing data")
+	}
+
+	return nil
+}
+
 // updateMapEntry - deletes are special, they are handled differently based on options
 //
 //	container type etc. We have to have special handling for each
@@ -78,7 +169,12 @@ func (d *Differ) updateMapEntry(c *ChangeValue, m, k, v *reflect.Value) {
 
 	switch c.change.Type {
 	case DELETE:
+		//the delete happened within the entry, such as a slice element, so the
+		//updated copy must be stored back
 		if c.HasFlag(FlagDeleted) {
+			if v != nil && v.IsValid() {
+				m.SetMapIndex(*k, *v)
+			}
 			return
 		}
 
@@ -9,8 +9,9 @@ import (
 	"strconv"
 )
 
-//renderSlice - handle slice rendering for patch
-func (d *Differ) renderSlice(c *ChangeValue) {
+//renderSlice - handle slice rendering for patch. Returns false if the path doesn't
+//              lead to an element of the slice, leaving the slice untouched
+func (d *Differ) renderSlice(c *ChangeValue) bool {
 
 	var err error
 	field := c.change.Path[c.pos]
@@ -20,20 +21,30 @@ func (d *Differ) renderSlice(c *ChangeValue) {
 		//if struct element is has identifier, use it instead
 		if identifier(d.TagName, reflect.Zero(c.target.Type().Elem())) != nil {
 			for c.index = 0; c.index < c.Len(); c.index++ {
-				if identifier(d.TagName, c.Index(c.index)) == field {
+				if d.identifies(c.Index(c.index), field) {
 					break
 				}
 			}
 		} else {
 			c.AddError(NewErrorf("invalid index in path. %s is not a number", field).
 				WithCause(err))
+			c.SetFlag(FlagFailed)
+			return false
 		}
 	}
 	var x reflect.Value
-	if c.Len() > c.index {
+	if d.SliceLCS && c.change.Type == CREATE && c.pos == len(c.change.Path)-1 &&
+		c.index >= 0 && c.index < c.Len() && c.target.CanSet() {
+		x = c.InsertArrayElement(c.index)
+	} else if c.Len() > c.index {
 		x = c.Index(c.index)
 	} else if c.change.Type == CREATE && !c.HasFlag(OptionNoCreate) {
-		x = c.NewArrayElement()
+		//keep the element at its position, unless only matching values may be touched
+		if c.index > c.Len() && !c.HasFlag(OptionOmitUnequal) && c.target.CanSet() {
+			x = c.GrowArrayElement(c.index)
+		} else {
+			x = c.NewArrayElement()
+		}
 	}
 	if !x.IsValid() {
 		if !c.HasFlag(OptionOmitUnequal) {
@@ -56,6 +67,17 @@ func (d *Differ) renderSlice(c *ChangeValue) {
 		c.index = -1 //no existing element to delete so don't bother
 	}
 	c.swap(&x) //containers must swap out the parent Value
+	return true
+}
+
+//identifies returns true if the path segment is the identifier of the element, as
+//rendered by diff. Composite identifiers are compared by their textual form
+func (d *Differ) identifies(v reflect.Value, field string) bool {
+	id := identifier(d.TagName, v)
+	if d.StructMapKeys {
+		return idComplex(id) == field
+	}
+	return idstring(id) == field
 }
 
 //deleteSliceEntry - deletes are special, they are handled differently based on options
@@ -67,6 +89,9 @@ func (d *Differ) deleteSliceEntry(c *ChangeValue) {
 		c.ParentSet(reflect.MakeSlice(c.parent.Type(), 0, 0), d.ConvertCompatibleTypes)
 		c.SetFlag(FlagDeleted)
 		//for a slice with multiple elements
+	} else if c.index != -1 && d.SliceLCS { //keep the order of the remaining elements
+		c.ParentSet(reflect.AppendSlice(c.parent.Slice(0, c.index), c.parent.Slice(c.index+1, c.ParentLen())), d.ConvertCompatibleTypes)
+		c.SetFlag(FlagDeleted)
 	} else if c.index != -1 { //this is an array delete the element from the parent
 		c.ParentIndex(c.index).Set(c.ParentIndex(c.ParentLen() - 1))
 		c.ParentSet(c.parent.Slice(0, c.ParentLen()-1), d.ConvertCompatibleTypes)
@@ -21,6 +21,10 @@ func getNestedFields(v reflect.Value, flattenEmbedded bool) []structField {
 
 		if fv.Kind() == reflect.Struct && f.Anonymous && flattenEmbedded {
 			fields = append(fields, getNestedFields(fv, flattenEmbedded)...)
+		} else if fv.Kind() == reflect.Ptr && !fv.IsNil() && fv.Elem().Kind() == reflect.Struct && f.Anonymous && flattenEmbedded {
+			// the pointer itself is kept, so that it can still be deleted as a whole
+			fields = append(fields, getNestedFields(fv.Elem(), flattenEmbedded)...)
+			fields = append(fields, structField{f, fv})
 		} else {
 			fields = append(fields, structField{f, fv})
 		}
@@ -29,15 +33,16 @@ func getNestedFields(v reflect.Value, flattenEmbedded bool) []structField {
 	return fields
 }
 
-//patchStruct - handles the rendering of a struct field
-func (d *Differ) patchStruct(c *ChangeValue) {
+//patchStruct - handles the rendering of a struct field. returns false if
+//              the target has no field matching the path
+func (d *Differ) patchStruct(c *ChangeValue) bool {
 
 	field := c.change.Path[c.pos]
 
 	structFields := getNestedFields(*c.target, d.FlattenEmbeddedStructs)
 	for _, structField := range structFields {
 		f := structField.f
-		tname := tagName(d.TagName, f)
+		tname := tagName(d.TagName, f, d.TagFallback...)
 		if tname == "-" {
 			continue
 		}
@@ -53,9 +58,63 @@ func (d *Differ) patchStruct(c *ChangeValue) {
 				c.SetFlag(OptionImmutable)
 			}
 			c.swap(&x)
-			break
+			return true
+		}
+	}
+
+	c.AddError(NewErrorf("Field %s not found on type %s", field, c.target.Type()))
+	c.SetFlag(FlagFailed)
+	return false
+}
+
+//convertStruct copies the fields of v to a new value of type t, matching
+//fields by their tag name. Only used when v is a struct that can't be
+//converted to t directly, otherwise v is returned as is
+func (d *Differ) convertStruct(v reflect.Value, t reflect.Type) reflect.Value {
+	sv := reflect.Indirect(v)
+	st := t
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+
+	if sv.Kind() != reflect.Struct || st.Kind() != reflect.Struct || sv.Type().ConvertibleTo(st) {
+		return v
+	}
+
+	fields := make(map[string]reflect.Value)
+	for _, sf := range getNestedFields(sv, d.FlattenEmbeddedStructs) {
+		if name := d.fieldName(sf.f); name != "-" && sf.f.PkgPath == "" {
+			fields[name] = sf.v
 		}
 	}
+
+	nv := reflect.New(st).Elem()
+	for _, sf := range getNestedFields(nv, d.FlattenEmbeddedStructs) {
+		fv, ok := fields[d.fieldName(sf.f)]
+		if !ok || !sf.v.CanSet() {
+			continue
+		}
+
+		fv = d.convertStruct(fv, sf.v.Type())
+		switch {
+		case fv.Type().ConvertibleTo(sf.v.Type()):
+			sf.v.Set(fv.Convert(sf.v.Type()))
+		case sf.v.Kind() == reflect.Ptr && fv.Type().ConvertibleTo(sf.v.Type().Elem()):
+			p := reflect.New(sf.v.Type().Elem())
+			p.Elem().Set(fv.Convert(p.Elem().Type()))
+			sf.v.Set(p)
+		}
+	}
+
+	return nv
+}
+
+//fieldName returns the name of the field as used in change paths
+func (d *Differ) fieldName(f reflect.StructField) string {
+	if name := tagName(d.TagName, f, d.TagFallback...); name != "" {
+		return name
+	}
+	return f.Name
 }
 
 //track and zero out struct members
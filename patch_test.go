@@ -2,6 +2,7 @@ package diff_test
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
 	"time"
 
@@ -40,6 +41,12 @@ func TestPatch(t *testing.T) {
 				diff.Change{Type: diff.DELETE, Path: []string{"1"}, From: 2},
 			},
 		},
+		{
+			"map-of-slices-delete", &map[string][]int{"a": {1, 2}}, &map[string][]int{"a": {1}},
+			diff.Changelog{
+				diff.Change{Type: diff.DELETE, Path: []string{"a", "1"}, From: 2},
+			},
+		},
 		{
 			"uint-slice-insert-delete", &[]uint{1, 2, 3}, &[]uint{1, 3, 4},
 			diff.Changelog{
@@ -310,6 +317,286 @@ func TestPatch(t *testing.T) {
 		assert.Nil(t, t1["after"])
 	})
 
+	t.Run("double-pointer", func(t *testing.T) {
+		type tdp struct {
+			V **int `diff:"v"`
+		}
+
+		one, two := 1, 2
+		p1, p2 := &one, &two
+		var np *int
+
+		cases := []struct {
+			Name string
+			A, B tdp
+		}{
+			{"outer-nil-to-value", tdp{}, tdp{V: &p1}},
+			{"inner-nil-to-value", tdp{V: &np}, tdp{V: &p1}},
+			{"value-to-inner-nil", tdp{V: &p1}, tdp{V: &np}},
+			{"value-to-outer-nil", tdp{V: &p1}, tdp{}},
+			{"value-to-value", tdp{V: &p1}, tdp{V: &p2}},
+			{"outer-nil-to-inner-nil", tdp{}, tdp{V: &np}},
+			{"inner-nil-to-outer-nil", tdp{V: &np}, tdp{}},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.Name, func(t *testing.T) {
+				changelog, err := diff.Diff(tc.A, tc.B)
+				require.NoError(t, err)
+				require.Len(t, changelog, 1)
+
+				target := tc.A
+				patchLog := diff.Patch(changelog, &target)
+				assert.False(t, patchLog.HasErrors())
+				assert.Equal(t, tc.B, target)
+			})
+		}
+	})
+
+	t.Run("nil-pointer-to-slice-and-map", func(t *testing.T) {
+		type tps struct {
+			Slice *[]int          `diff:"slice,create"`
+			Map   *map[string]int `diff:"map,create"`
+		}
+
+		s0, s1 := []int{1}, []int{1, 2}
+		m0, m1 := map[string]int{"a": 1}, map[string]int{"a": 2, "b": 3}
+
+		changelog, err := diff.Diff(tps{Slice: &s0, Map: &m0}, tps{Slice: &s1, Map: &m1})
+		require.NoError(t, err)
+
+		var target tps
+		patchLog := diff.Patch(changelog, &target)
+		assert.False(t, patchLog.HasErrors())
+
+		require.NotNil(t, target.Slice)
+		require.NotNil(t, target.Map)
+		// the created element keeps its index, the gap before it is zero filled
+		assert.Equal(t, []int{0, 2}, *target.Slice)
+		assert.Equal(t, map[string]int{"a": 2, "b": 3}, *target.Map)
+
+		changelog, err = diff.Diff(tps{}, tps{Slice: &s1, Map: &m1})
+		require.NoError(t, err)
+
+		target = tps{}
+		patchLog = diff.Patch(changelog, &target)
+		assert.False(t, patchLog.HasErrors())
+		assert.Equal(t, tps{Slice: &s1, Map: &m1}, target)
+	})
+
+	t.Run("bytes", func(t *testing.T) {
+		type MyType struct {
+			Data []byte `diff:"data"`
+		}
+
+		a := MyType{Data: []byte{0x8a, 0x0e, 0x2d, 0x5e, 0x4f, 0x9f, 0x4d, 0x21, 0x9d, 0x2b, 0x6f, 0xd8, 0x6b, 0x8d, 0x62, 0x40}}
+		b := MyType{Data: []byte{0x3c, 0xb2, 0x45, 0x12, 0x4f, 0x9f, 0x4d, 0x21, 0x9d, 0x2b, 0x6f, 0xd8, 0x6b, 0x8d, 0x62, 0x41}}
+
+		changelog, err := diff.Diff(a, b)
+		require.NoError(t, err)
+		require.Len(t, changelog, 1)
+
+		target := MyType{}
+		patchLog := diff.Patch(changelog, &target)
+		assert.False(t, patchLog.HasErrors())
+		assert.Equal(t, b, target)
+
+		target = a
+		patchLog = diff.Patch(changelog, &target)
+		assert.False(t, patchLog.HasErrors())
+		assert.Equal(t, b, target)
+	})
+
+	t.Run("hex-encoded-bytes", func(t *testing.T) {
+		type tbytes struct {
+			Name string `diff:"name"`
+			Data []byte `diff:"data"`
+		}
+
+		a := tbytes{Name: "a", Data: []byte{0x00, 0x01, 0xfe}}
+		b := tbytes{Name: "b", Data: []byte{0xde, 0xad, 0xbe, 0xef}}
+
+		d, err := diff.NewDiffer(diff.HexEncodeBytes(true))
+		require.NoError(t, err)
+
+		changelog, err := d.Diff(a, b)
+		require.NoError(t, err)
+		require.Len(t, changelog, 2)
+		assert.Equal(t, []string{"data"}, changelog[1].Path)
+		assert.Equal(t, "0001fe", changelog[1].From)
+		assert.Equal(t, "deadbeef", changelog[1].To)
+
+		// hex strings survive a round trip through json
+		js, err := json.Marshal(changelog)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(js, &changelog))
+
+		target := a
+		patchLog := d.Patch(changelog, &target)
+		assert.False(t, patchLog.HasErrors())
+		assert.Equal(t, b, target)
+
+		target = tbytes{}
+		patchLog = d.Patch(changelog, &target)
+		assert.False(t, patchLog.HasErrors())
+		assert.Equal(t, b.Data, target.Data)
+
+		changelog, err = d.Diff(nil, &b)
+		require.NoError(t, err)
+		require.Len(t, changelog, 2)
+		assert.Equal(t, diff.CREATE, changelog[1].Type)
+		assert.Equal(t, "deadbeef", changelog[1].To)
+
+		changelog = diff.Changelog{{Type: diff.UPDATE, Path: []string{"data"}, From: "0001fe", To: "zz"}}
+		patchLog = d.Patch(changelog, &target)
+		assert.True(t, patchLog.HasErrors())
+	})
+
+	t.Run("map-non-string-keys", func(t *testing.T) {
+		type Key struct {
+			Region string `msgpack:"region"`
+			Name   string `msgpack:"name"`
+		}
+		type Content struct {
+			Text   string `diff:"text"`
+			Number int    `diff:"number"`
+		}
+		type CustomKey string
+
+		cases := []struct {
+			Name    string
+			Options []func(d *diff.Differ) error
+			A, B    func() interface{}
+		}{
+			{
+				"int-keys", nil,
+				func() interface{} { return &map[int]string{1: "one", 2: "two", 5: "five"} },
+				func() interface{} { return &map[int]string{1: "uno", 5: "five", 53: "fifty-three"} },
+			},
+			{
+				"int-keys-struct-map-key-support", []func(d *diff.Differ) error{diff.StructMapKeySupport()},
+				func() interface{} { return &map[int]string{1: "one", 2: "two", 5: "five"} },
+				func() interface{} { return &map[int]string{1: "uno", 5: "five", 53: "fifty-three"} },
+			},
+			{
+				"uint-keys", nil,
+				func() interface{} { return &map[uint8]bool{1: true} },
+				func() interface{} { return &map[uint8]bool{1: false, 2: true} },
+			},
+			{
+				"custom-string-keys", nil,
+				func() interface{} { return &map[CustomKey]int{"a": 1} },
+				func() interface{} { return &map[CustomKey]int{"a": 2, "b": 3} },
+			},
+			{
+				"struct-keys", []func(d *diff.Differ) error{diff.StructMapKeySupport()},
+				func() interface{} {
+					return &map[Key]Content{
+						{"eu", "a"}: {"first", 1},
+						{"us", "b"}: {"second", 2},
+					}
+				},
+				func() interface{} {
+					return &map[Key]Content{
+						{"eu", "a"}: {"first", 10},
+						{"us", "c"}: {"third", 3},
+					}
+				},
+			},
+			{
+				"nested-struct-keys-create", []func(d *diff.Differ) error{diff.StructMapKeySupport()},
+				func() interface{} { return &map[string]map[Key]int{} },
+				func() interface{} { return &map[string]map[Key]int{"x": {{"eu", "a"}: 1, {"us", "b"}: 50}} },
+			},
+			{
+				"nested-int-keys-create", []func(d *diff.Differ) error{diff.StructMapKeySupport()},
+				func() interface{} { return &map[string]map[int]int{} },
+				func() interface{} { return &map[string]map[int]int{"x": {1: 1, 300: 2}} },
+			},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.Name, func(t *testing.T) {
+				d, err := diff.NewDiffer(tc.Options...)
+				require.NoError(t, err)
+
+				a, b := tc.A(), tc.B()
+				changelog, err := d.Diff(a, b)
+				require.NoError(t, err)
+				require.NotEmpty(t, changelog)
+
+				patchLog := d.Patch(changelog, a)
+				assert.False(t, patchLog.HasErrors())
+				assert.Equal(t, b, a)
+			})
+		}
+	})
+
+	t.Run("json-registered-types", func(t *testing.T) {
+		type B struct {
+			Name string `diff:"name"`
+		}
+		type A struct {
+			Value interface{} `diff:"value"`
+		}
+
+		diff.RegisterType("patch_test.B", B{})
+		diff.RegisterType("patch_test.*B", &B{})
+
+		cases := []struct {
+			Name string
+			A, B A
+		}{
+			{"struct", A{}, A{Value: B{Name: "b"}}},
+			{"pointer", A{}, A{Value: &B{Name: "b"}}},
+			{"struct-to-nil", A{Value: B{Name: "b"}}, A{}},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.Name, func(t *testing.T) {
+				changelog, err := diff.Diff(tc.A, tc.B, diff.TypedValues(true))
+				require.NoError(t, err)
+				require.Len(t, changelog, 1)
+
+				js, err := json.Marshal(changelog)
+				require.NoError(t, err)
+
+				var unmarshaled diff.Changelog
+				require.NoError(t, json.Unmarshal(js, &unmarshaled))
+				assert.Equal(t, changelog[0].From, unmarshaled[0].From)
+				assert.Equal(t, changelog[0].To, unmarshaled[0].To)
+
+				target := tc.A
+				patchLog := diff.Patch(unmarshaled, &target)
+				assert.False(t, patchLog.HasErrors())
+				assert.Equal(t, tc.B, target)
+			})
+		}
+
+		// without the option, registered types are encoded and decoded as before
+		changelog, err := diff.Diff(A{}, A{Value: B{Name: "b"}})
+		require.NoError(t, err)
+		js, err := json.Marshal(changelog)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"type":"update","path":["value"],"from":null,"to":{"Name":"b"}}]`, string(js))
+
+		var unmarshaled diff.Changelog
+		require.NoError(t, json.Unmarshal(js, &unmarshaled))
+		assert.Equal(t, map[string]interface{}{"Name": "b"}, unmarshaled[0].To)
+
+		// unregistered types are decoded as before
+		changelog, err = diff.Diff(A{Value: 1}, A{Value: tmstruct{Foo: "a"}}, diff.TypedValues(true), diff.AllowTypeMismatch(true))
+		require.NoError(t, err)
+		js, err = json.Marshal(changelog)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"type":"update","path":["value"],"from":1,"to":{"Foo":"a","Bar":0}}]`, string(js))
+
+		unmarshaled = nil
+		require.NoError(t, json.Unmarshal(js, &unmarshaled))
+		assert.Equal(t, float64(1), unmarshaled[0].From)
+		assert.Equal(t, map[string]interface{}{"Foo": "a", "Bar": float64(0)}, unmarshaled[0].To)
+	})
+
 	t.Run("pointer-with-converted-type", func(t *testing.T) {
 		type tps struct {
 			S *int
@@ -349,3 +636,618 @@ func TestPatch(t *testing.T) {
 		assert.False(t, patchLog.HasErrors())
 	})
 }
+
+func TestMerge3(t *testing.T) {
+	type doc struct {
+		Title  string            `diff:"title"`
+		Body   string            `diff:"body"`
+		Tags   []string          `diff:"tags"`
+		Labels map[string]string `diff:"labels"`
+	}
+
+	base := doc{Title: "draft", Body: "hello", Tags: []string{"a"}, Labels: map[string]string{"env": "dev"}}
+
+	cases := []struct {
+		Name      string
+		A, B      doc
+		Expected  doc
+		Conflicts []diff.Conflict
+	}{
+		{
+			"clean",
+			doc{Title: "final", Body: "hello", Tags: []string{"a"}, Labels: map[string]string{"env": "dev"}},
+			doc{Title: "draft", Body: "hello", Tags: []string{"a", "b"}, Labels: map[string]string{"env": "dev", "team": "x"}},
+			doc{Title: "final", Body: "hello", Tags: []string{"a", "b"}, Labels: map[string]string{"env": "dev", "team": "x"}},
+			nil,
+		},
+		{
+			"conflicting-field",
+			doc{Title: "mine", Body: "hello world", Tags: []string{"a"}, Labels: map[string]string{"env": "dev"}},
+			doc{Title: "theirs", Body: "hello", Tags: []string{"a"}, Labels: map[string]string{"env": "prod"}},
+			doc{Title: "draft", Body: "hello world", Tags: []string{"a"}, Labels: map[string]string{"env": "prod"}},
+			[]diff.Conflict{
+				{Path: []string{"title"}, BaseVal: "draft", AVal: "mine", BVal: "theirs"},
+			},
+		},
+		{
+			"identical-changes",
+			doc{Title: "final", Body: "hello", Tags: []string{"a"}, Labels: map[string]string{}},
+			doc{Title: "final", Body: "bye", Tags: []string{"a"}, Labels: map[string]string{}},
+			doc{Title: "final", Body: "bye", Tags: []string{"a"}, Labels: map[string]string{}},
+			nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			target := doc{Title: base.Title, Body: base.Body, Tags: []string{"a"}, Labels: map[string]string{"env": "dev"}}
+
+			pl, conflicts, err := diff.Merge3(base, tc.A, tc.B, &target)
+			require.NoError(t, err)
+			assert.False(t, pl.HasErrors())
+			assert.Equal(t, tc.Conflicts, conflicts)
+			assert.Equal(t, tc.Expected, target)
+		})
+	}
+}
+
+func TestMerge3LeavesDiffer(t *testing.T) {
+	type key struct {
+		Name string
+	}
+
+	d, err := diff.NewDiffer()
+	require.NoError(t, err)
+
+	base := map[string]int{"a": 1}
+	target := map[string]int{"a": 1}
+
+	_, _, err = d.Merge3(base, map[string]int{"a": 2}, map[string]int{"a": 1, "b": 3}, &target)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 2, "b": 3}, target)
+
+	// struct map keys are still rendered as before the merge
+	cl, err := d.Diff(map[key]int{{"x"}: 1}, map[key]int{{"x"}: 2})
+	require.NoError(t, err)
+	require.Len(t, cl, 1)
+	assert.False(t, d.StructMapKeys)
+	assert.Equal(t, []string{"{x}"}, cl[0].Path)
+}
+
+func TestMerge3Overlapping(t *testing.T) {
+	type doc struct {
+		Title string              `diff:"title"`
+		Meta  map[string][]string `diff:"meta"`
+	}
+
+	base := doc{Title: "draft", Meta: map[string][]string{"k": {"x"}}}
+	deleted := doc{Title: "draft", Meta: map[string][]string{}}
+	appended := doc{Title: "final", Meta: map[string][]string{"k": {"x", "y"}}}
+
+	cases := []struct {
+		Name     string
+		A, B     doc
+		Conflict diff.Conflict
+	}{
+		{
+			"outer-a",
+			deleted, appended,
+			diff.Conflict{Path: []string{"meta", "k"}, BaseVal: []string{"x"}, AVal: nil, BVal: []string{"x", "y"}},
+		},
+		{
+			"outer-b",
+			appended, deleted,
+			diff.Conflict{Path: []string{"meta", "k"}, BaseVal: []string{"x"}, AVal: []string{"x", "y"}, BVal: nil},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			target := doc{Title: "draft", Meta: map[string][]string{"k": {"x"}}}
+
+			pl, conflicts, err := diff.Merge3(base, tc.A, tc.B, &target)
+			require.NoError(t, err)
+			assert.False(t, pl.HasErrors())
+			assert.Equal(t, []diff.Conflict{tc.Conflict}, conflicts)
+
+			// neither side of the conflict is applied, while other changes are
+			assert.Equal(t, doc{Title: "final", Meta: map[string][]string{"k": {"x"}}}, target)
+		})
+	}
+}
+
+func TestPatchTransactional(t *testing.T) {
+	type account struct {
+		Name    string         `diff:"name"`
+		Balance int            `diff:"balance"`
+		Tags    []string       `diff:"tags"`
+		Limits  map[string]int `diff:"limits"`
+		Owner   *tmstruct      `diff:"owner"`
+		notes   map[string]string
+	}
+
+	original := func() account {
+		return account{
+			Name:    "checking",
+			Balance: 10,
+			Tags:    []string{"a", "b"},
+			Limits:  map[string]int{"daily": 100},
+			Owner:   &tmstruct{Foo: "one", Bar: 1},
+			notes:   map[string]string{"x": "y"},
+		}
+	}
+
+	t.Run("applied", func(t *testing.T) {
+		target := original()
+		cl := diff.Changelog{
+			{Type: diff.UPDATE, Path: []string{"balance"}, From: 10, To: 20},
+			{Type: diff.UPDATE, Path: []string{"tags", "1"}, From: "b", To: "c"},
+		}
+
+		require.NoError(t, diff.PatchTransactional(cl, &target))
+		assert.Equal(t, 20, target.Balance)
+		assert.Equal(t, []string{"a", "c"}, target.Tags)
+	})
+
+	t.Run("rolled-back", func(t *testing.T) {
+		target := original()
+		tags, limits, owner := target.Tags, target.Limits, target.Owner
+
+		cl := diff.Changelog{
+			{Type: diff.UPDATE, Path: []string{"balance"}, From: 10, To: 20},
+			{Type: diff.UPDATE, Path: []string{"tags", "1"}, From: "b", To: "c"},
+			{Type: diff.UPDATE, Path: []string{"limits", "daily"}, From: 100, To: 0},
+			{Type: diff.UPDATE, Path: []string{"owner", "foo"}, From: "one", To: "two"},
+			{Type: diff.UPDATE, Path: []string{"missing"}, From: 1, To: 2},
+			{Type: diff.UPDATE, Path: []string{"name"}, From: "checking", To: "savings"},
+		}
+
+		err := diff.PatchTransactional(cl, &target)
+		require.Error(t, err)
+		assert.Equal(t, original(), target)
+
+		// the original values may have been modified in place, but are no longer referenced
+		assert.Equal(t, "c", tags[1])
+		assert.Equal(t, 0, limits["daily"])
+		assert.Equal(t, "two", owner.Foo)
+	})
+
+	t.Run("cyclic", func(t *testing.T) {
+		type ring struct {
+			Name string `diff:"name"`
+			Next *ring  `diff:"next"`
+		}
+
+		target := ring{Name: "a"}
+		target.Next = &target
+
+		cl := diff.Changelog{
+			{Type: diff.UPDATE, Path: []string{"name"}, From: "a", To: "b"},
+			{Type: diff.UPDATE, Path: []string{"missing"}, From: 1, To: 2},
+		}
+
+		require.Error(t, diff.PatchTransactional(cl, &target))
+		assert.Equal(t, "a", target.Name)
+		assert.Equal(t, "a", target.Next.Name)
+		assert.True(t, target.Next.Next == target.Next)
+	})
+
+	t.Run("invalid-target", func(t *testing.T) {
+		assert.Error(t, diff.PatchTransactional(diff.Changelog{}, original()))
+	})
+}
+
+func TestPatchReverseMap(t *testing.T) {
+	type entry struct {
+		Name  string `diff:"name"`
+		Count int    `diff:"count"`
+	}
+
+	type key struct {
+		ID int `diff:"id"`
+	}
+
+	cases := []struct {
+		Name    string
+		A, B    interface{}
+		Options []func(d *diff.Differ) error
+	}{
+		{"delete", map[string]int{"a": 1, "b": 2}, map[string]int{"a": 1}, nil},
+		{"update", map[string]int{"a": 1, "b": 2}, map[string]int{"a": 3, "b": 2}, nil},
+		{"mixed", map[string]int{"a": 1, "b": 2}, map[string]int{"a": 3, "c": 4}, nil},
+		{"struct-values", map[string]entry{"a": {"x", 1}, "b": {"y", 2}}, map[string]entry{"a": {"x", 5}}, nil},
+		{"int-keys", map[int]string{1: "one", 2: "two"}, map[int]string{2: "deux", 3: "three"}, nil},
+		{"struct-keys", map[key]string{{1}: "one", {2}: "two"}, map[key]string{{2}: "deux"}, []func(d *diff.Differ) error{diff.StructMapKeySupport()}},
+		{"nested", map[string]map[string]int{"a": {"x": 1}, "b": {"y": 2}}, map[string]map[string]int{"a": {"x": 2, "z": 3}}, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			d, err := diff.NewDiffer(tc.Options...)
+			require.NoError(t, err)
+
+			cl, err := d.Diff(tc.A, tc.B)
+			require.NoError(t, err)
+
+			target := reflect.New(reflect.TypeOf(tc.B))
+			copied, err := d.Diff(nil, tc.B)
+			require.NoError(t, err)
+			require.False(t, d.Patch(copied, target.Interface()).HasErrors())
+
+			pl := d.Patch(cl.Reverse(), target.Interface())
+			assert.False(t, pl.HasErrors())
+			assert.Equal(t, tc.A, target.Elem().Interface())
+		})
+	}
+}
+
+func TestUnpatch(t *testing.T) {
+	type doc struct {
+		Name   string            `diff:"name"`
+		Count  *int              `diff:"count"`
+		Tags   []string          `diff:"tags"`
+		Labels map[string]string `diff:"labels"`
+	}
+
+	one := 1
+	a := doc{
+		Name:   "a",
+		Tags:   []string{"x", "y"},
+		Labels: map[string]string{"a": "1", "b": "2"},
+	}
+	b := doc{
+		Name:   "b",
+		Count:  &one,
+		Tags:   []string{"x"},
+		Labels: map[string]string{"a": "2", "c": "3"},
+	}
+
+	cl, err := diff.Diff(a, b)
+	require.Nil(t, err)
+
+	target := a
+	target.Tags = []string{"x", "y"}
+	target.Labels = map[string]string{"a": "1", "b": "2"}
+
+	pl := diff.Patch(cl, &target)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, b, target)
+
+	pl = diff.Unpatch(cl, &target)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, a, target)
+}
+
+func TestPatchSliceGap(t *testing.T) {
+	type series struct {
+		Points []int    `diff:"points"`
+		Tags   []string `diff:"tags,omitunequal"`
+	}
+
+	cl := diff.Changelog{
+		{Type: diff.CREATE, Path: []string{"points", "5"}, To: 6},
+		{Type: diff.CREATE, Path: []string{"points", "2"}, To: 3},
+		{Type: diff.CREATE, Path: []string{"tags", "5"}, To: "x"},
+	}
+
+	target := series{Points: []int{1, 2}, Tags: []string{"a", "b"}}
+	pl := diff.Patch(cl, &target)
+	assert.False(t, pl.HasErrors())
+	assert.True(t, pl[0].HasFlag(diff.FlagCreated))
+
+	assert.Equal(t, []int{1, 2, 3, 0, 0, 6}, target.Points)
+	assert.Equal(t, 6, target.Points[5])
+
+	// with omitunequal, no values are made up to fill the gap
+	assert.Equal(t, []string{"a", "b", "x"}, target.Tags)
+}
+
+func TestPatchNoDelete(t *testing.T) {
+	type settings struct {
+		Name   string            `diff:"name"`
+		Tags   []string          `diff:"tags"`
+		Labels map[string]string `diff:"labels"`
+	}
+
+	a := settings{Name: "a", Tags: []string{"x", "y", "z"}, Labels: map[string]string{"env": "dev", "team": "core"}}
+	b := settings{Name: "b", Tags: []string{"x"}, Labels: map[string]string{"env": "prod", "owner": "ops"}}
+
+	cl, err := diff.Diff(a, b)
+	require.NoError(t, err)
+	require.Len(t, cl.FilterFunc(func(c diff.Change) bool { return c.Type == diff.DELETE }), 3)
+
+	target := a
+	target.Tags = append([]string{}, a.Tags...)
+	target.Labels = map[string]string{"env": "dev", "team": "core"}
+
+	pl := diff.PatchNoDelete(cl, &target)
+	assert.False(t, pl.HasErrors())
+	for _, ple := range pl {
+		assert.False(t, ple.HasFlag(diff.FlagDeleted))
+	}
+
+	assert.Equal(t, settings{
+		Name:   "b",
+		Tags:   []string{"x", "y", "z"},
+		Labels: map[string]string{"env": "prod", "team": "core", "owner": "ops"},
+	}, target)
+}
+
+func TestApplyChange(t *testing.T) {
+	type settings struct {
+		Name  string `diff:"name"`
+		Count int    `diff:"count"`
+	}
+
+	target := settings{Name: "a", Count: 1}
+
+	ple := diff.ApplyChange(diff.Change{Type: diff.UPDATE, Path: []string{"name"}, From: "a", To: "b"}, &target)
+	assert.Nil(t, ple.Errors)
+	assert.True(t, ple.HasFlag(diff.FlagUpdated))
+	assert.True(t, ple.HasFlag(diff.FlagApplied))
+	assert.False(t, ple.HasFlag(diff.FlagFailed))
+	assert.Equal(t, settings{Name: "b", Count: 1}, target)
+
+	ple = diff.ApplyChange(diff.Change{Type: diff.UPDATE, Path: []string{"missing"}, From: 1, To: 2}, &target)
+	assert.NotNil(t, ple.Errors)
+	assert.True(t, ple.HasFlag(diff.FlagFailed))
+	assert.Equal(t, settings{Name: "b", Count: 1}, target)
+}
+
+func TestPatchCompositeIdentifier(t *testing.T) {
+	type server struct {
+		Region string `diff:"region,identifier"`
+		Name   string `diff:"name,identifier"`
+		Size   int    `diff:"size"`
+	}
+
+	a := []server{{"eu", "a", 1}, {"us", "a", 1}, {"eu", "b", 1}}
+	b := []server{{"us", "a", 2}, {"eu", "a", 5}, {"eu", "b", 1}}
+
+	cl, err := diff.Diff(a, b)
+	require.NoError(t, err)
+	require.Len(t, cl, 2)
+	assert.Equal(t, []string{"eu,a", "size"}, cl[0].Path)
+
+	pl := diff.Patch(cl, &a)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, []server{{"eu", "a", 5}, {"us", "a", 2}, {"eu", "b", 1}}, a)
+}
+
+func TestPatchStrict(t *testing.T) {
+	type settings struct {
+		Name  string `diff:"name"`
+		Count int    `diff:"count"`
+		Level int    `diff:"level"`
+	}
+
+	cl := diff.Changelog{
+		{Type: diff.UPDATE, Path: []string{"name"}, From: "a", To: "b"},
+		{Type: diff.UPDATE, Path: []string{"count"}, From: 1, To: "not a number"},
+		{Type: diff.UPDATE, Path: []string{"level"}, From: 1, To: 2},
+	}
+
+	target := settings{Name: "a", Count: 1, Level: 1}
+
+	d, err := diff.NewDiffer()
+	require.NoError(t, err)
+
+	pl, err := d.PatchStrict(cl, &target)
+	require.Error(t, err)
+	require.Len(t, pl, 2)
+	assert.Nil(t, pl[0].Errors)
+	assert.Equal(t, err, pl[1].Errors)
+	assert.Equal(t, settings{Name: "b", Count: 1, Level: 1}, target)
+
+	// the best effort patch carries on past the error
+	target = settings{Name: "a", Count: 1, Level: 1}
+	pl = d.Patch(cl, &target)
+	require.Len(t, pl, 3)
+	assert.Equal(t, settings{Name: "b", Count: 1, Level: 2}, target)
+
+	target = settings{Name: "a", Count: 1, Level: 1}
+	pl, err = diff.PatchStrict(cl[:1], &target)
+	require.NoError(t, err)
+	assert.Len(t, pl, 1)
+}
+
+func TestPatchDistinctStructTypes(t *testing.T) {
+	type Origin struct {
+		Country string `diff:"country"`
+		Farm    string `diff:"farm"`
+	}
+
+	type Fruit struct {
+		ID        int            `diff:"ID"`
+		Name      string         `diff:"name"`
+		Weight    float32        `diff:"weight"`
+		Nutrients []string       `diff:"nutrients"`
+		Labels    map[string]int `diff:"labs"`
+		Color     string         `diff:"color"`
+		Origin    Origin         `diff:"origin"`
+	}
+
+	type Source struct {
+		Nation string `diff:"country"`
+		Grower string `diff:"farm"`
+	}
+
+	type Produce struct {
+		Identifier int64            `diff:"ID"`
+		Title      string           `diff:"name"`
+		Weight     float64          `diff:"weight"`
+		Nutrients  []string         `diff:"nutrients"`
+		Labels     map[string]int64 `diff:"labs"`
+		Source     *Source          `diff:"origin"`
+	}
+
+	a := Fruit{ID: 1, Name: "Green Apple", Weight: 1, Nutrients: []string{"vitamin a"}, Labels: map[string]int{"likes": 1}, Color: "green", Origin: Origin{"NZ", "Orchard"}}
+	b := Fruit{ID: 2, Name: "Red Apple", Weight: 1, Nutrients: []string{"vitamin a"}, Labels: map[string]int{"likes": 2}, Color: "red", Origin: Origin{"IE", "Farm"}}
+
+	d, err := diff.NewDiffer(diff.ConvertCompatibleTypes(), diff.StructWholeThreshold(0.9))
+	require.NoError(t, err)
+
+	cl, err := d.Diff(a, b)
+	require.NoError(t, err)
+
+	// origin has changed as a whole, and is converted to the target's source field
+	require.Len(t, cl.Filter([]string{"origin"}), 1)
+
+	target := Produce{Identifier: 1, Title: "Green Apple", Weight: 1, Nutrients: []string{"vitamin a"}, Labels: map[string]int64{"likes": 1}}
+	pl := d.Patch(cl, &target)
+
+	assert.Equal(t, Produce{
+		Identifier: 2,
+		Title:      "Red Apple",
+		Weight:     1,
+		Nutrients:  []string{"vitamin a"},
+		Labels:     map[string]int64{"likes": 2},
+		Source:     &Source{Nation: "IE", Grower: "Farm"},
+	}, target)
+
+	require.Equal(t, uint(1), pl.ErrorCount())
+	for _, ple := range pl {
+		if ple.Errors != nil {
+			assert.Equal(t, []string{"color"}, ple.Path)
+			assert.True(t, ple.HasFlag(diff.FlagFailed))
+			assert.Contains(t, ple.Errors.Error(), "Field color not found")
+		}
+	}
+}
+
+func TestPatchLogStats(t *testing.T) {
+	type settings struct {
+		Name   string         `diff:"name"`
+		Count  int            `diff:"count"`
+		Tags   []string       `diff:"tags"`
+		Labels map[string]int `diff:"labels"`
+	}
+
+	cl := diff.Changelog{
+		{Type: diff.UPDATE, Path: []string{"name"}, From: "a", To: "b"},
+		{Type: diff.UPDATE, Path: []string{"count"}, From: 1, To: "not a number"},
+		{Type: diff.CREATE, Path: []string{"labels", "new"}, To: 1},
+		{Type: diff.DELETE, Path: []string{"tags", "0"}, From: "x"},
+		{Type: diff.UPDATE, Path: []string{"missing"}, From: 1, To: 2},
+		{Type: diff.WARNING, Path: []string{"tags"}, To: "a warning"},
+	}
+
+	target := settings{Name: "a", Count: 1, Tags: []string{"x", "y"}, Labels: map[string]int{}}
+	pl := diff.Patch(cl, &target)
+
+	assert.Equal(t, settings{Name: "b", Count: 1, Tags: []string{"y"}, Labels: map[string]int{"new": 1}}, target)
+	assert.Equal(t, diff.PatchStats{Applied: 2, Failed: 2, Ignored: 1, Created: 1, Deleted: 1}, pl.Stats())
+	assert.Equal(t, diff.PatchStats{}, diff.PatchLog{}.Stats())
+}
+
+type compiledAddress struct {
+	Street string `diff:"street"`
+	Number int    `diff:"number"`
+}
+
+type compiledEmbedded struct {
+	Note string `diff:"note"`
+}
+
+type compiledPerson struct {
+	compiledEmbedded
+	Name    string            `diff:"name"`
+	Age     int               `diff:"age"`
+	Score   float64           `diff:"score"`
+	Fixed   string            `diff:"fixed,immutable"`
+	Tags    []string          `diff:"tags"`
+	Labels  map[string]string `diff:"labels"`
+	Address compiledAddress   `diff:"address"`
+	Manager *compiledAddress  `diff:"manager"`
+}
+
+func TestCompilePatch(t *testing.T) {
+	a := compiledPerson{
+		compiledEmbedded: compiledEmbedded{Note: "a"},
+		Name:             "one",
+		Age:              1,
+		Score:            1.5,
+		Fixed:            "x",
+		Tags:             []string{"a"},
+		Labels:           map[string]string{"a": "1"},
+		Address:          compiledAddress{Street: "first", Number: 1},
+	}
+	b := compiledPerson{
+		compiledEmbedded: compiledEmbedded{Note: "b"},
+		Name:             "two",
+		Age:              0,
+		Score:            2.5,
+		Fixed:            "y",
+		Tags:             []string{"a", "b"},
+		Labels:           map[string]string{"a": "2"},
+		Address:          compiledAddress{Street: "second", Number: 2},
+		Manager:          &compiledAddress{Street: "third"},
+	}
+
+	cases := []struct {
+		Name    string
+		Options []func(d *diff.Differ) error
+	}{
+		{"default", nil},
+		{"flatten", []func(d *diff.Differ) error{diff.FlattenEmbeddedStructs()}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			d, err := diff.NewDiffer(tc.Options...)
+			require.Nil(t, err)
+
+			cl, err := d.Diff(a, b)
+			require.Nil(t, err)
+
+			cp := d.CompilePatch(cl, &compiledPerson{})
+
+			// a compiled patch can be applied repeatedly, matching a normal patch each time
+			for i := 0; i < 2; i++ {
+				expected := a
+				expected.Tags = []string{"a"}
+				expected.Labels = map[string]string{"a": "1"}
+				epl := d.Patch(cl, &expected)
+
+				actual := a
+				actual.Tags = []string{"a"}
+				actual.Labels = map[string]string{"a": "1"}
+				apl := cp.Patch(&actual)
+
+				assert.Equal(t, epl, apl)
+				assert.Equal(t, expected, actual)
+			}
+		})
+	}
+
+	// targets of another type are patched normally
+	cl, err := diff.Diff(a.Address, b.Address)
+	require.Nil(t, err)
+
+	m := map[string]interface{}{}
+	pl := diff.CompilePatch(cl, a.Address).Patch(&m)
+	assert.Equal(t, diff.Patch(cl, &map[string]interface{}{}), pl)
+}
+
+func BenchmarkCompiledPatch(b *testing.B) {
+	from := compiledPerson{Name: "one", Age: 1, Score: 1.5, Address: compiledAddress{Street: "first", Number: 1}}
+	to := compiledPerson{Name: "two", Age: 2, Score: 2.5, Address: compiledAddress{Street: "second", Number: 2}}
+
+	cl, err := diff.Diff(from, to)
+	require.Nil(b, err)
+
+	b.Run("patch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			target := from
+			diff.Patch(cl, &target)
+		}
+	})
+
+	b.Run("compiled", func(b *testing.B) {
+		cp := diff.CompilePatch(cl, from)
+		for i := 0; i < b.N; i++ {
+			target := from
+			cp.Patch(&target)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// PatchTransactional applies a changelog to target as a whole. If any change fails to apply,
+// target is restored to its original state and the error is returned
+func PatchTransactional(cl Changelog, target interface{}) error {
+	d, _ := NewDiffer()
+	return d.PatchTransactional(cl, target)
+}
+
+// PatchTransactional applies a changelog to target as a whole, rolling back on failure
+func (d *Differ) PatchTransactional(cl Changelog, target interface{}) error {
+	t := reflect.ValueOf(target)
+	if t.Kind() != reflect.Ptr || t.IsNil() {
+		return NewError("target must be a non-nil pointer")
+	}
+
+	backup := deepCopy(t.Elem())
+
+	for _, ple := range d.Patch(cl, target) {
+		if !ple.HasFlag(FlagFailed) && !ple.HasFlag(FlagInvalidTarget) {
+			continue
+		}
+
+		t.Elem().Set(backup)
+
+		if ple.Errors != nil {
+			return NewError("unable to apply changelog, changes rolled back", ple.Errors)
+		}
+		return NewError("unable to apply changelog, changes rolled back")
+	}
+
+	return nil
+}
+
+// deepCopy returns a copy of v that shares no memory with it. Values that refer back to
+// themselves are copied once, so the copy holds the same cycles
+func deepCopy(v reflect.Value) reflect.Value {
+	return copyValue(v, make(map[copied]reflect.Value))
+}
+
+// copied identifies a pointer or map that has already been copied
+type copied struct {
+	p uintptr
+	t reflect.Type
+}
+
+func copyValue(v reflect.Value, seen map[copied]reflect.Value) reflect.Value {
+	c := reflect.New(v.Type()).Elem()
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			k := copied{v.Pointer(), v.Type()}
+			if p, ok := seen[k]; ok {
+				c.Set(p)
+				break
+			}
+
+			p := reflect.New(v.Type().Elem())
+			seen[k] = p
+			p.Elem().Set(copyValue(v.Elem(), seen))
+			c.Set(p)
+		}
+	case reflect.Interface:
+		if !v.IsNil() {
+			c.Set(copyValue(v.Elem(), seen))
+		}
+	case reflect.Slice:
+		if !v.IsNil() {
+			s := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+			for i := 0; i < v.Len(); i++ {
+				s.Index(i).Set(copyValue(v.Index(i), seen))
+			}
+			c.Set(s)
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(copyValue(v.Index(i), seen))
+		}
+	case reflect.Map:
+		if !v.IsNil() {
+			k := copied{v.Pointer(), v.Type()}
+			if m, ok := seen[k]; ok {
+				c.Set(m)
+				break
+			}
+
+			m := reflect.MakeMapWithSize(v.Type(), v.Len())
+			seen[k] = m
+			for _, mk := range v.MapKeys() {
+				m.SetMapIndex(copyValue(mk, seen), copyValue(v.MapIndex(mk), seen))
+			}
+			c.Set(m)
+		}
+	case reflect.Struct:
+		// times hold a shared location, which is compared by identity
+		if isTime(v) {
+			c.Set(reflect.ValueOf(exportInterface(v)))
+			break
+		}
+
+		for i := 0; i < v.NumField(); i++ {
+			f := c.Field(i)
+			if !f.CanSet() {
+				f = reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
+			}
+			f.Set(copyValue(v.Field(i), seen))
+		}
+	default:
+		if v.CanInterface() {
+			c.Set(v)
+		} else {
+			c.Set(reflect.ValueOf(exportInterface(v)).Convert(v.Type()))
+		}
+	}
+
+	return c
+}
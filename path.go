@@ -0,0 +1,73 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Path is the location of a change. It is a plain []string, so it can be
+// assigned to and from Change.Path freely
+type Path []string
+
+// String returns a human readable, dot separated representation of the path.
+// Map keys encoded with StructMapKeySupport are decoded to their readable form
+func (p Path) String() string {
+	parts := make([]string, len(p))
+	for i, e := range p {
+		parts[i] = decodeSegment(e)
+	}
+	return strings.Join(parts, ".")
+}
+
+// Equal returns true if both paths contain the same segments
+func (p Path) Equal(o Path) bool {
+	if len(p) != len(o) {
+		return false
+	}
+
+	for i := range p {
+		if p[i] != o[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// HasPrefix returns true if the path begins with all segments of prefix
+func (p Path) HasPrefix(prefix Path) bool {
+	if len(prefix) > len(p) {
+		return false
+	}
+
+	return p[:len(prefix)].Equal(prefix)
+}
+
+// decodeSegment attempts to decode a msgpack encoded path segment, as produced
+// by idComplex. Segments that are not encoded are returned as they are
+func decodeSegment(s string) string {
+	if s == "" || (s[0] >= 0x20 && s[0] < 0x80) {
+		return s
+	}
+
+	if utf8.ValidString(s) && s[0] >= 0x20 {
+		return s
+	}
+
+	r := bytes.NewReader([]byte(s))
+
+	var v interface{}
+	if err := msgpack.NewDecoder(r).Decode(&v); err != nil || r.Len() > 0 {
+		return s
+	}
+
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,111 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"reflect"
+	"time"
+)
+
+// DiffStructShaped compares two structs, returning the changes in a nested map that mirrors the
+// struct's fields by their tag names. Each field holds its *Change, or nil if it is unchanged
+func DiffStructShaped(a, b interface{}) (map[string]interface{}, error) {
+	d, err := NewDiffer()
+	if err != nil {
+		return nil, err
+	}
+	return d.DiffStructShaped(a, b)
+}
+
+// DiffStructShaped compares two structs, returning the changes in a nested map that mirrors the
+// struct's fields by their tag names. Nested structs are nested maps. Changes below a field that
+// isn't a struct, such as slice elements or map keys, are nested under the field by their path
+func (d *Differ) DiffStructShaped(a, b interface{}) (map[string]interface{}, error) {
+	t := reflect.TypeOf(a)
+	if t == nil {
+		t = reflect.TypeOf(b)
+	}
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, ErrTypeMismatch
+	}
+
+	cl, err := d.Diff(a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	shape := d.structShape(t, map[reflect.Type]bool{})
+
+	for i := range cl {
+		if len(cl[i].Path) == 0 {
+			continue
+		}
+
+		m := shape
+		last := len(cl[i].Path) - 1
+		for _, p := range cl[i].Path[:last] {
+			n, ok := m[p].(map[string]interface{})
+			if !ok {
+				n = make(map[string]interface{})
+				m[p] = n
+			}
+			m = n
+		}
+
+		m[cl[i].Path[last]] = &cl[i]
+	}
+
+	return shape, nil
+}
+
+// structShape returns a map holding nil for each of the struct's fields, and a nested map for
+// fields holding structs
+func (d *Differ) structShape(t reflect.Type, seen map[reflect.Type]bool) map[string]interface{} {
+	shape := make(map[string]interface{})
+
+	seen[t] = true
+	defer delete(seen, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		tname := tagName(d.TagName, f, d.TagFallback...)
+		if tname == "-" {
+			continue
+		}
+
+		if tname == "" {
+			tname = f.Name
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if ft.Kind() != reflect.Struct || ft == reflect.TypeOf(time.Time{}) || seen[ft] {
+			shape[tname] = nil
+			continue
+		}
+
+		if d.FlattenEmbeddedStructs && f.Anonymous {
+			for k, v := range d.structShape(ft, seen) {
+				shape[k] = v
+			}
+			continue
+		}
+
+		shape[tname] = d.structShape(ft, seen)
+	}
+
+	return shape
+}
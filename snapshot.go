@@ -0,0 +1,163 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Snapshot records every value held by v as a "created" entry in a changelog.
+// Unlike StructValues, v may be of any supported kind, and nested structs, maps
+// and slices are walked down to their individual values
+func Snapshot(v interface{}) (Changelog, error) {
+	d, err := NewDiffer()
+	if err != nil {
+		return nil, err
+	}
+	return d.Snapshot(v)
+}
+
+// Snapshot records every value held by v as a "created" entry in a changelog
+func (d *Differ) Snapshot(v interface{}) (Changelog, error) {
+	d.cl = Changelog{}
+	return d.cl, d.snapshot([]string{}, reflect.ValueOf(v))
+}
+
+// Reconstruct rebuilds a value from a changelog produced by Snapshot into target
+func Reconstruct(cl Changelog, target interface{}) PatchLog {
+	d, _ := NewDiffer()
+	return d.Reconstruct(cl, target)
+}
+
+// Reconstruct rebuilds a value from a changelog produced by Snapshot into target
+func (d *Differ) Reconstruct(cl Changelog, target interface{}) PatchLog {
+	return d.Patch(cl, target)
+}
+
+func (d *Differ) snapshot(path []string, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return d.snapshot(path, v.Elem())
+	case reflect.Struct:
+		if isTime(v) {
+			break
+		}
+		return d.snapshotStruct(path, v)
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Len() == 0 {
+			break
+		}
+		return d.snapshotMap(path, v)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Len() == 0 || v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		return d.snapshotSlice(path, v)
+	case reflect.Array:
+		return d.snapshotSlice(path, v)
+	}
+
+	d.cl.Add(CREATE, path, nil, snapshotValue(v))
+
+	return nil
+}
+
+func (d *Differ) snapshotStruct(path []string, v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		tname := tagName(d.TagName, field, d.TagFallback...)
+
+		if tname == "-" {
+			continue
+		}
+
+		if tname == "" {
+			tname = field.Name
+		}
+
+		vf := v.Field(i)
+		if diffIgnored(vf) {
+			continue
+		}
+
+		fpath := copyAppend(path, tname)
+
+		if d.Filter != nil && !d.Filter(fpath, v.Type(), field) {
+			continue
+		}
+
+		if err := d.snapshot(fpath, vf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Differ) snapshotMap(path []string, v reflect.Value) error {
+	keys := v.MapKeys()
+	ids := make([]string, len(keys))
+
+	for i, k := range keys {
+		ids[i] = fmt.Sprint(exportInterface(k))
+
+		if d.StructMapKeys {
+			b, err := msgpack.Marshal(exportInterface(k))
+			if err != nil {
+				return err
+			}
+			ids[i] = string(b)
+		}
+	}
+
+	sortKeys(keys, ids)
+
+	for i, k := range keys {
+		if err := d.snapshot(copyAppend(path, ids[i]), v.MapIndex(k)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Differ) snapshotSlice(path []string, v reflect.Value) error {
+	for i := 0; i < v.Len(); i++ {
+		if err := d.snapshot(copyAppend(path, fmt.Sprint(i)), v.Index(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// snapshotValue returns the value to record for v. Empty maps and slices, as
+// well as byte slices, are copied so the snapshot doesn't share memory with v
+func snapshotValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Map:
+		return reflect.MakeMap(v.Type()).Interface()
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(s, reflect.ValueOf(exportInterface(v)))
+		return s.Interface()
+	}
+
+	return exportInterface(v)
+}
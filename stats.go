@@ -0,0 +1,82 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import "time"
+
+// DiffStats holds the number of changes of each type in a changelog
+type DiffStats struct {
+	Creates int
+	Updates int
+	Deletes int
+}
+
+// Total returns the number of creates, updates and deletes
+func (s DiffStats) Total() int {
+	return s.Creates + s.Updates + s.Deletes
+}
+
+// Stats counts the changes of each type. Unchanged values and warnings aren't counted
+func (cl Changelog) Stats() DiffStats {
+	var s DiffStats
+
+	for _, c := range cl {
+		s.add(c)
+	}
+
+	return s
+}
+
+func (s *DiffStats) add(c Change) {
+	switch c.Type {
+	case CREATE:
+		s.Creates++
+	case UPDATE:
+		s.Updates++
+	case DELETE:
+		s.Deletes++
+	}
+}
+
+// StreamSummary is held by the END change that completes a streamed diff
+type StreamSummary struct {
+	Stats    DiffStats
+	Duration time.Duration
+}
+
+// PatchStats holds the number of patch log entries carrying each flag. An entry may be
+// counted more than once, such as a slice element that was both created and applied
+type PatchStats struct {
+	Applied int
+	Failed  int
+	Ignored int
+	Created int
+	Deleted int
+}
+
+// Stats counts the entries of the patch log by their flags
+func (p PatchLog) Stats() PatchStats {
+	var s PatchStats
+
+	for _, ple := range p {
+		if ple.HasFlag(FlagApplied) {
+			s.Applied++
+		}
+		if ple.HasFlag(FlagFailed) {
+			s.Failed++
+		}
+		if ple.HasFlag(FlagIgnored) {
+			s.Ignored++
+		}
+		if ple.HasFlag(FlagCreated) {
+			s.Created++
+		}
+		if ple.HasFlag(FlagDeleted) {
+			s.Deleted++
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,46 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// TruncatedValue takes the place of a From or To value that exceeded the
+// size set with MaxValueBytes. Size is the length of its msgpack encoding
+type TruncatedValue struct {
+	Size int `json:"size"`
+}
+
+func init() {
+	RegisterType("diff.TruncatedValue", TruncatedValue{})
+}
+
+// truncate replaces values that are larger than n bytes when encoded
+func (cl Changelog) truncate(n int) {
+	for i := range cl {
+		cl[i].From = truncateValue(cl[i].From, n)
+		cl[i].To = truncateValue(cl[i].To, n)
+	}
+}
+
+// isTruncated returns true if the value the change sets was truncated, so it can't be applied
+func (c Change) isTruncated() bool {
+	_, ok := c.To.(TruncatedValue)
+	return ok
+}
+
+func truncateValue(v interface{}, n int) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	b, err := msgpack.Marshal(v)
+	if err != nil || len(b) <= n {
+		return v
+	}
+
+	return TruncatedValue{Size: len(b)}
+}
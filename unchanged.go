@@ -0,0 +1,91 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diff
+
+import (
+	"errors"
+	"reflect"
+)
+
+// errChanged stops a diff as soon as the first change has been found
+var errChanged = errors.New("changed")
+
+// UnchangedSubtrees returns the paths of the struct fields of a and b that are entirely unchanged
+func UnchangedSubtrees(a, b interface{}, opts ...func(d *Differ) error) ([][]string, error) {
+	d, err := NewDiffer(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return d.UnchangedSubtrees(a, b)
+}
+
+// UnchangedSubtrees returns the paths of the struct fields of a and b that are entirely unchanged.
+// Fields holding structs that have changed are descended into, so that the deepest unchanged
+// fields are found. Diffing a field stops as soon as its first change has been found
+func (d *Differ) UnchangedSubtrees(a, b interface{}) ([][]string, error) {
+	return d.unchangedSubtrees([]string{}, reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+func (d *Differ) unchangedSubtrees(path []string, a, b reflect.Value) ([][]string, error) {
+	for a.Kind() == reflect.Ptr && b.Kind() == reflect.Ptr && !a.IsNil() && !b.IsNil() {
+		a, b = a.Elem(), b.Elem()
+	}
+
+	if a.Kind() != reflect.Struct || !b.IsValid() || a.Type() != b.Type() || isTime(a) {
+		return nil, nil
+	}
+
+	var roots [][]string
+
+	for i := 0; i < a.NumField(); i++ {
+		field := a.Type().Field(i)
+		tname := tagName(d.TagName, field, d.TagFallback...)
+
+		if tname == "-" {
+			continue
+		}
+
+		if tname == "" {
+			tname = field.Name
+		}
+
+		fpath := copyAppend(path, tname)
+
+		changed, err := d.changed(fpath, a.Field(i), b.Field(i))
+		if err != nil {
+			return nil, err
+		}
+
+		if !changed {
+			roots = append(roots, fpath)
+			continue
+		}
+
+		sub, err := d.unchangedSubtrees(fpath, a.Field(i), b.Field(i))
+		if err != nil {
+			return nil, err
+		}
+
+		roots = append(roots, sub...)
+	}
+
+	return roots, nil
+}
+
+// changed returns true if there is any change between a and b
+func (d *Differ) changed(path []string, a, b reflect.Value) (bool, error) {
+	err := d.stream(path, a, b, func(c Change) error {
+		if c.Type != EQUAL && c.Type != WARNING {
+			return errChanged
+		}
+		return nil
+	})
+
+	if err == errChanged {
+		return true, nil
+	}
+
+	return false, err
+}